@@ -0,0 +1,468 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	humanize "github.com/dustin/go-humanize"
+	"golang.org/x/net/publicsuffix"
+)
+
+const cookiesMaxLines = 15
+
+type storedCookie struct {
+	Name, Value, Domain, Path string
+	Expires                   time.Time // zero for session cookies
+	Secure, HttpOnly          bool
+	// set without a Domain attribute, it is only sent to that exact host
+	HostOnly bool
+}
+
+func (c storedCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+func (c storedCookie) same(o storedCookie) bool {
+	return c.Domain == o.Domain && c.Path == o.Path && c.Name == o.Name
+}
+
+// cookieJar is an http.CookieJar following RFC 6265 that keeps its cookies
+// in the store, requests are sent from goroutines so it is locked.
+type cookieJar struct {
+	mu      sync.Mutex
+	store   *Store
+	cookies []storedCookie
+	// why the cookies of the last response weren't saved, they are kept in
+	// memory until the app is closed
+	err error
+}
+
+func newCookieJar(store *Store) (*cookieJar, error) {
+	cookies, err := store.GetCookies()
+	if err != nil {
+		return nil, err
+	}
+	j := &cookieJar{store: store}
+	now := time.Now()
+	for _, c := range cookies {
+		if !c.expired(now) {
+			j.cookies = append(j.cookies, c)
+		} else if err := store.DeleteCookie(c); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+	for _, cookie := range cookies {
+		c := storedCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   strings.TrimPrefix(strings.ToLower(cookie.Domain), "."),
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		if c.Domain == "" || net.ParseIP(host) != nil {
+			if c.Domain != "" && c.Domain != host {
+				continue
+			}
+			c.Domain, c.HostOnly = host, true
+		} else if !domainMatch(host, c.Domain) {
+			continue // a server can't set cookies for another domain
+		} else if isPublicSuffix(c.Domain) {
+			// nor for all the sites of a public suffix, like net/http/cookiejar
+			// it is a host-only cookie when the host is the suffix itself
+			if c.Domain != host {
+				continue
+			}
+			c.HostOnly = true
+		}
+		if !strings.HasPrefix(c.Path, "/") {
+			c.Path = defaultCookiePath(u.Path)
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			c.Expires = now
+		case cookie.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		default:
+			c.Expires = cookie.Expires
+		}
+
+		i := slices.IndexFunc(j.cookies, c.same)
+		var err error
+		switch {
+		case c.expired(now) && i >= 0:
+			j.cookies = slices.Delete(j.cookies, i, i+1)
+			err = j.store.DeleteCookie(c)
+		case c.expired(now):
+		case i >= 0:
+			j.cookies[i] = c
+			err = j.store.SaveCookie(c)
+		default:
+			j.cookies = append(j.cookies, c)
+			err = j.store.SaveCookie(c)
+		}
+		if err != nil {
+			j.err = fmt.Errorf("failed to save cookie %s: %w", c.Name, err)
+		}
+	}
+}
+
+// saveErr returns and forgets why cookies weren't saved, if they weren't
+func (j *cookieJar) saveErr() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	err := j.err
+	j.err = nil
+	return err
+}
+
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	host := strings.ToLower(u.Hostname())
+	path := cmp.Or(u.EscapedPath(), "/")
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	now := time.Now()
+	var matches []storedCookie
+	for _, c := range j.cookies {
+		if c.expired(now) || c.Secure && !secure || !pathMatch(path, c.Path) {
+			continue
+		}
+		if c.HostOnly && host != c.Domain || !c.HostOnly && !domainMatch(host, c.Domain) {
+			continue
+		}
+		matches = append(matches, c)
+	}
+	// more specific paths first
+	slices.SortStableFunc(matches, func(a, b storedCookie) int { return len(b.Path) - len(a.Path) })
+	var cookies []*http.Cookie
+	for _, c := range matches {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+// list returns the cookies by domain, path and name
+func (j *cookieJar) list() []storedCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cookies := slices.Clone(j.cookies)
+	slices.SortFunc(cookies, func(a, b storedCookie) int {
+		return cmp.Or(strings.Compare(a.Domain, b.Domain), strings.Compare(a.Path, b.Path), strings.Compare(a.Name, b.Name))
+	})
+	return cookies
+}
+
+func (j *cookieJar) set(c storedCookie) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	// a cookie removed while it was edited is kept again, as it is saved
+	if i := slices.IndexFunc(j.cookies, c.same); i >= 0 {
+		j.cookies[i] = c
+	} else {
+		j.cookies = append(j.cookies, c)
+	}
+	return j.store.SaveCookie(c)
+}
+
+func (j *cookieJar) remove(c storedCookie) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies = slices.DeleteFunc(j.cookies, c.same)
+	return j.store.DeleteCookie(c)
+}
+
+func (j *cookieJar) clear(domain string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies = slices.DeleteFunc(j.cookies, func(c storedCookie) bool { return c.Domain == domain })
+	return j.store.DeleteCookies(domain)
+}
+
+// isPublicSuffix reports if anyone can register names under the domain,
+// e.g. com, co.uk or github.io.
+func isPublicSuffix(domain string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	return strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil
+}
+
+func pathMatch(path, cookiePath string) bool {
+	if !strings.HasPrefix(path, cookiePath) {
+		return false
+	}
+	return len(path) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") || path[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath is the directory of the request path
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// cookieManager lists the cookies of the jar to inspect, edit and delete them
+type cookieManager struct {
+	cursor int
+	input  textinput.Model
+	// the cookie whose value is edited, nil otherwise. The responses may
+	// change the list meanwhile, the cursor can end up on another one.
+	edited   *storedCookie
+	lastView int
+}
+
+func makeCookieManager() cookieManager {
+	return cookieManager{input: makeInputField("", "value")}
+}
+
+func openCookies(m model) model {
+	m.cookies.lastView = view
+	m.cookies.cursor = 0
+	view = cookiesView
+	return m
+}
+
+func updateCookies(m model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	cookies := m.jar.list()
+	if m.cookies.edited != nil {
+		switch {
+		case key.Matches(msg, keys.Done, keys.Select):
+			c := *m.cookies.edited
+			c.Value = m.cookies.input.Value()
+			if err := m.jar.set(c); err != nil {
+				m.err = fmt.Errorf("failed to save cookie %s: %w", c.Name, err)
+				return m, nil
+			}
+			if i := slices.IndexFunc(m.jar.list(), c.same); i >= 0 {
+				m.cookies.cursor = i
+			}
+			m.cookies.edited = nil
+			m.cookies.input.Blur()
+			mode = normal
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.cookies.input, cmd = m.cookies.input.Update(msg)
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(msg, keys.Back, keys.Cookies):
+		view = m.cookies.lastView
+	case key.Matches(msg, keys.Down):
+		m.cookies.cursor = min(m.cookies.cursor+1, max(len(cookies)-1, 0))
+	case key.Matches(msg, keys.Up):
+		m.cookies.cursor = max(m.cookies.cursor-1, 0)
+	case len(cookies) == 0:
+	case key.Matches(msg, keys.Select):
+		edited := cookies[m.cookies.cursor]
+		m.cookies.edited = &edited
+		m.cookies.input.SetValue(edited.Value)
+		mode = insert
+		return m, m.cookies.input.Focus()
+	case key.Matches(msg, keys.Delete):
+		if err := m.jar.remove(cookies[m.cookies.cursor]); err != nil {
+			m.err = fmt.Errorf("failed to delete cookie %s: %w", cookies[m.cookies.cursor].Name, err)
+			break
+		}
+		m.cookies.cursor = max(min(m.cookies.cursor, len(cookies)-2), 0)
+	case key.Matches(msg, keys.ClearCookies):
+		if err := m.jar.clear(cookies[m.cookies.cursor].Domain); err != nil {
+			m.err = fmt.Errorf("failed to clear the cookies of %s: %w", cookies[m.cookies.cursor].Domain, err)
+			break
+		}
+		m.cookies.cursor = 0
+	}
+	return m, nil
+}
+
+func renderCookies(m model) string {
+	title := lg.NewStyle().Bold(true).Render("Cookies")
+	faint := lg.NewStyle().Foreground(placeHolderColor)
+	cookies := m.jar.list()
+	lines := []string{title, ""}
+	if len(cookies) == 0 {
+		lines = append(lines, faint.Render("  no cookies yet, they are kept from the responses"))
+	}
+	start := max(0, m.cookies.cursor-cookiesMaxLines+1)
+	for i := start; i < min(len(cookies), start+cookiesMaxLines); i++ {
+		c := cookies[i]
+		cursor := "  "
+		if i == m.cookies.cursor {
+			cursor = "> "
+		}
+		value := c.Value
+		if m.cookies.edited != nil && c.same(*m.cookies.edited) {
+			value = m.cookies.input.View()
+		}
+		expires := "session"
+		if !c.Expires.IsZero() {
+			expires = "expires " + humanize.Time(c.Expires)
+		}
+		var flags []string
+		if c.Secure {
+			flags = append(flags, "Secure")
+		}
+		if c.HttpOnly {
+			flags = append(flags, "HttpOnly")
+		}
+		line := cursor + lg.NewStyle().Width(24).MaxWidth(24).Render(c.Domain+c.Path) + " " +
+			c.Name + "=" + value + "  " + faint.Render(expires+" "+strings.Join(flags, " "))
+		lines = append(lines, lg.NewStyle().MaxWidth(rightPanelWidth).Render(line))
+	}
+	help := []string{keys.Select.Help().Key + " edit value"}
+	for _, b := range []key.Binding{keys.Delete, keys.ClearCookies, keys.Back} {
+		help = append(help, b.Help().Key+" "+b.Help().Desc)
+	}
+	lines = append(lines, "", faint.Render(strings.Join(help, "  ")))
+	return secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(strings.Join(lines, "\n"))
+}
+
+// responseCookies parses the Set-Cookie headers of the response with all
+// their attributes, the ones a browser would reject are kept with a warning.
+func responseCookies(res *http.Response) []ResponseCookie {
+	var cookies []ResponseCookie
+	for _, line := range res.Header.Values("Set-Cookie") {
+		cookie, err := http.ParseSetCookie(line)
+		if err != nil {
+			name, _, _ := strings.Cut(line, "=")
+			cookies = append(cookies, ResponseCookie{
+				Name:     strings.TrimSpace(name),
+				Warnings: []string{"invalid Set-Cookie: " + err.Error()},
+			})
+			continue
+		}
+		c := ResponseCookie{
+			Name:        cookie.Name,
+			Value:       cookie.Value,
+			Domain:      cookie.Domain,
+			Path:        cookie.Path,
+			Expires:     cookie.Expires,
+			MaxAge:      cookie.MaxAge,
+			Secure:      cookie.Secure,
+			HttpOnly:    cookie.HttpOnly,
+			SameSite:    sameSiteNames[cookie.SameSite],
+			Partitioned: cookie.Partitioned,
+		}
+		c.Warnings = cookieWarnings(c, res.Request.URL)
+		cookies = append(cookies, c)
+	}
+	return cookies
+}
+
+var sameSiteNames = map[http.SameSite]string{
+	http.SameSiteLaxMode:    "Lax",
+	http.SameSiteStrictMode: "Strict",
+	http.SameSiteNoneMode:   "None",
+}
+
+// cookieWarnings returns why a browser would reject the cookie set by u
+func cookieWarnings(c ResponseCookie, u *url.URL) []string {
+	var warnings []string
+	host := strings.ToLower(u.Hostname())
+	// browsers treat localhost as a secure origin
+	secure := u.Scheme == "https" || host == "localhost" || net.ParseIP(host).IsLoopback()
+	domain := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+	if c.SameSite == "None" && !c.Secure {
+		warnings = append(warnings, "SameSite=None without Secure")
+	}
+	if c.Partitioned && !c.Secure {
+		warnings = append(warnings, "Partitioned without Secure")
+	}
+	if c.Secure && !secure {
+		warnings = append(warnings, "Secure cookie set over http")
+	}
+	if domain != "" && !domainMatch(host, domain) {
+		warnings = append(warnings, "Domain "+c.Domain+" doesn't match the host "+host)
+	} else if domain != "" && domain != host && isPublicSuffix(domain) {
+		warnings = append(warnings, "Domain "+c.Domain+" is a public suffix")
+	}
+	switch {
+	case strings.HasPrefix(c.Name, "__Secure-") && !c.Secure:
+		warnings = append(warnings, "__Secure- prefix without Secure")
+	case strings.HasPrefix(c.Name, "__Host-") && (!c.Secure || c.Domain != "" || c.Path != "/"):
+		warnings = append(warnings, "__Host- prefix needs Secure, Path=/ and no Domain")
+	}
+	return warnings
+}
+
+// cookieColumns fits the response cookies table in width, the warnings
+// column is hidden as they are shown under the table.
+func cookieColumns(width int) []table.Column {
+	// 2 for the table border and 2 for the padding of each cell
+	cellWidth := width - 2 - 5*2
+	name, value := cellWidth*16/100, cellWidth*20/100
+	return []table.Column{
+		{Title: "Name", Width: name},
+		{Title: "Value", Width: value},
+		{Title: "Domain/Path", Width: value},
+		{Title: "Expires", Width: cellWidth * 22 / 100},
+		{Title: "Attributes", Width: cellWidth - name - 2*value - cellWidth*22/100},
+		{Title: "Warnings", Width: 0},
+	}
+}
+
+// setCookieRows fills the response cookies table
+func setCookieRows(t *table.Model, cookies []ResponseCookie) {
+	rows := []table.Row{}
+	for _, c := range cookies {
+		name := c.Name
+		if len(c.Warnings) > 0 {
+			name = "⚠ " + name
+		}
+		expires := ""
+		switch {
+		case c.MaxAge < 0:
+			expires = "deleted"
+		case c.MaxAge > 0:
+			expires = "in " + (time.Duration(c.MaxAge) * time.Second).String()
+		case !c.Expires.IsZero():
+			expires = c.Expires.Local().Format("2006-01-02 15:04")
+		}
+		var attributes []string
+		if c.Secure {
+			attributes = append(attributes, "Secure")
+		}
+		if c.HttpOnly {
+			attributes = append(attributes, "HttpOnly")
+		}
+		if c.SameSite != "" {
+			attributes = append(attributes, "SameSite="+c.SameSite)
+		}
+		if c.Partitioned {
+			attributes = append(attributes, "Partitioned")
+		}
+		rows = append(rows, table.Row{
+			name, c.Value, c.Domain + c.Path, expires, strings.Join(attributes, " "), strings.Join(c.Warnings, ", "),
+		})
+	}
+	t.SetRows(rows)
+}
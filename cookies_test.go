@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCookieJarPublicSuffix(t *testing.T) {
+	tests := []struct {
+		url, domain string
+		kept        bool
+		hostOnly    bool
+	}{
+		{"https://www.example.com/", "example.com", true, false},
+		{"https://www.example.com/", "com", false, false},
+		{"https://shop.example.co.uk/", "co.uk", false, false},
+		{"https://shop.example.co.uk/", ".example.co.uk", true, false},
+		{"https://me.github.io/", "github.io", false, false},
+		// the suffix itself may only set cookies for itself
+		{"https://github.io/", "github.io", true, true},
+		{"http://localhost:8080/", "localhost", true, true},
+	}
+	for _, tt := range tests {
+		jar, err := newCookieJar(newTestStore(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, _ := url.Parse(tt.url)
+		jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "1", Domain: tt.domain}})
+		cookies := jar.list()
+		if kept := len(cookies) == 1; kept != tt.kept {
+			t.Errorf("%s with Domain=%s: kept %v, want %v", tt.url, tt.domain, kept, tt.kept)
+			continue
+		}
+		if tt.kept && cookies[0].HostOnly != tt.hostOnly {
+			t.Errorf("%s with Domain=%s: host-only %v, want %v", tt.url, tt.domain, cookies[0].HostOnly, tt.hostOnly)
+		}
+		if err := jar.saveErr(); err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+		}
+	}
+}
+
+// a store error keeps the cookie in memory and is reported once
+func TestCookieJarStoreError(t *testing.T) {
+	s := newTestStore(t)
+	jar, err := newCookieJar(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.conn.Close()
+	u, _ := url.Parse("https://example.com/login")
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "abc"}})
+	if cookies := jar.Cookies(u); len(cookies) != 1 || cookies[0].Value != "abc" {
+		t.Errorf("the cookie wasn't kept: %v", cookies)
+	}
+	if err := jar.saveErr(); err == nil {
+		t.Error("the store error wasn't reported")
+	}
+	if err := jar.saveErr(); err != nil {
+		t.Errorf("the error was reported twice: %v", err)
+	}
+}
+
+func TestCookieWarningsPublicSuffix(t *testing.T) {
+	u, _ := url.Parse("https://www.example.com/")
+	if warnings := cookieWarnings(ResponseCookie{Name: "id", Domain: "com", Secure: true}, u); len(warnings) != 1 {
+		t.Errorf("warnings = %v, want the public suffix", warnings)
+	}
+	if warnings := cookieWarnings(ResponseCookie{Name: "id", Domain: "example.com", Secure: true}, u); len(warnings) != 0 {
+		t.Errorf("warnings = %v, want none", warnings)
+	}
+}
+
+// the edited cookie is saved even if a response changed the list meanwhile
+func TestEditCookieListChanged(t *testing.T) {
+	m := newTestModel(t)
+	u, _ := url.Parse("https://example.com/")
+	m.jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}})
+	m = openCookies(m)
+	m = press(t, m, runes("j"))
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	m.jar.SetCookies(u, []*http.Cookie{{Name: "0", Value: "0"}})
+	m = press(t, m, runes("3"))
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	values := map[string]string{}
+	for _, c := range m.jar.list() {
+		values[c.Name] = c.Value
+	}
+	if values["0"] != "0" || values["a"] != "1" || values["b"] != "23" {
+		t.Errorf("cookies %v, want b=23", values)
+	}
+	if c := m.jar.list()[m.cookies.cursor]; c.Name != "b" {
+		t.Errorf("the cursor is on %s", c.Name)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"mime"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type (
+	// editorFinishedMsg carries back the file edited for the field of view
+	editorFinishedMsg struct {
+		view  int
+		index int
+		path  string
+		err   error
+	}
+	pagerFinishedMsg struct {
+		path string
+		err  error
+	}
+)
+
+var bodyExtensions = map[string]string{"Json": ".json", "Xml": ".xml", "Plain": ".txt"}
+
+// editorCommand splits the editor so values like "code --wait" work
+func editorCommand(path string, env ...string) *exec.Cmd {
+	for _, name := range env {
+		if args := strings.Fields(os.Getenv(name)); len(args) > 0 {
+			return exec.Command(args[0], append(args[1:], path)...)
+		}
+	}
+	return exec.Command("vi", path)
+}
+
+func writeTempFile(content, ext string, perm os.FileMode) (string, error) {
+	f, err := os.CreateTemp("", "tuisomnium-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		return "", err
+	}
+	return f.Name(), f.Chmod(perm)
+}
+
+// openEditor edits the content in $VISUAL/$EDITOR, the result is read back
+// by the editorFinishedMsg handler.
+func openEditor(content, ext string, view, index int) tea.Cmd {
+	path, err := writeTempFile(content, ext, 0600)
+	if err != nil {
+		return func() tea.Msg { return errMsg{err} }
+	}
+	return tea.ExecProcess(editorCommand(path, "VISUAL", "EDITOR"), func(err error) tea.Msg {
+		return editorFinishedMsg{view, index, path, err}
+	})
+}
+
+// openPager shows the content read only in $PAGER, falling back to $EDITOR.
+func openPager(content, ext string) tea.Cmd {
+	path, err := writeTempFile(content, ext, 0400)
+	if err != nil {
+		return func() tea.Msg { return errMsg{err} }
+	}
+	return tea.ExecProcess(editorCommand(path, "PAGER", "VISUAL", "EDITOR"), func(err error) tea.Msg {
+		return pagerFinishedMsg{path, err}
+	})
+}
+
+// responseExtension guesses the extension of a response from its Content-Type
+func responseExtension(headers []NameValue) string {
+	for _, header := range headers {
+		if !strings.EqualFold(header.Name, "Content-Type") {
+			continue
+		}
+		mediaType, _, err := mime.ParseMediaType(header.Value)
+		if err != nil {
+			break
+		}
+		switch {
+		case strings.HasSuffix(mediaType, "json"):
+			return ".json"
+		case strings.HasSuffix(mediaType, "xml"):
+			return ".xml"
+		case mediaType == "text/html":
+			return ".html"
+		}
+	}
+	return ".txt"
+}
+
+// editField opens the field under the cursor of the current view
+func editField(m model) tea.Cmd {
+	switch view {
+	case bodyContentView:
+		if m.body.cursor == 0 { // No body option
+			return nil
+		}
+		ext := bodyExtensions[m.body.options[m.body.cursor].type_]
+		return openEditor(m.body.field.Value(), ext, view, m.body.cursor)
+	case authContentView:
+		authType := m.auth.options[m.auth.cursor]
+		if len(authType.fields) == 0 {
+			return nil
+		}
+		return openEditor(authType.fields[authType.cursor].Value(), ".txt", view, authType.cursor)
+	case headersContentView:
+		return openEditor(m.headers.fields[m.headers.cursor].Value(), ".txt", view, m.headers.cursor)
+	case responseView:
+		req := m.requests.items[m.requests.cursor]
+		if len(req.Responses) == 0 {
+			return nil
+		}
+		res := req.Responses[m.history.cursor]
+		return openPager(res.Body, responseExtension(res.Headers))
+	}
+	return nil
+}
+
+// editorFinished puts the edited content back in its field,
+// a failing editor (e.g. vim's :cq) discards the changes.
+func editorFinished(m model, msg editorFinishedMsg) (model, tea.Cmd) {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		return m, nil
+	}
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		return m, func() tea.Msg { return errMsg{err} }
+	}
+	content := string(data)
+	switch msg.view {
+	case bodyContentView:
+		m.body.options[msg.index].value = content
+		if m.body.cursor == msg.index {
+			m.body.field.SetValue(content)
+		}
+	case authContentView:
+		// editors usually add a trailing newline, single line fields can't keep it
+		authType := m.auth.options[m.auth.cursor]
+		authType.fields[msg.index].SetValue(strings.TrimRight(content, "\r\n"))
+	case headersContentView:
+		m.headers.fields[msg.index].SetValue(strings.TrimRight(content, "\r\n"))
+	}
+	return m, nil
+}
+
+// nameValues collects the non empty pairs of name/value input fields
+func nameValues(fields []textinput.Model) []NameValue {
+	var pairs []NameValue
+	for i := range len(fields) / 2 {
+		name := fields[i*2].Value()
+		value := fields[i*2+1].Value()
+		if len(name) == 0 && len(value) == 0 {
+			continue
+		}
+		pairs = append(pairs, NameValue{name, value})
+	}
+	return pairs
+}
@@ -11,8 +11,19 @@ const (
 	digestAuth
 	tokenAuth
 	customAuth
+	oauth2Auth
+	awsAuth
+	apiKeyAuth
+	hmacAuth
+	jwtAuth
 )
 
+var apiKeyLabels = []string{"Key name", "Value", "Location"}
+
+// labeledFieldWidth is the width of the fields of auth types with a label
+// per field, their values can be longer than what fits the pane.
+const labeledFieldWidth = 40
+
 type authType struct {
 	name   string
 	fields []textinput.Model
@@ -32,11 +43,31 @@ func makeAuth() auth {
 			{"Digest", []textinput.Model{makeInputField("", ""), makeInputField("", "")}, 0},
 			{"Bearer Token", []textinput.Model{makeInputField("", ""), makeInputField("", "")}, 0},
 			{"Custom", []textinput.Model{makeInputField("", "")}, 0},
+			{"OAuth 2.0", makeLabeledFields(
+				"client_credentials, password, refresh_token or authorization_code",
+				"https://...", "https://... (authorization_code)", defaultRedirectURL, "", "", "", "", "", "",
+			), 0},
+			{"AWS SigV4", makeLabeledFields("AKIA...", "", "optional", "us-east-1", "execute-api"), 0},
+			{"API Key", makeLabeledFields("X-API-Key", "", "header or query"), 0},
+			{"HMAC", makeLabeledFields("sha1, sha256 or sha512", "", "optional", "X-Key-Id", "X-Signature", "X-Timestamp", "hex or base64"), 0},
+			{"JWT", makeLabeledFields(
+				"HS256, RS256, ES256 (or 384, 512)", "secret, or @path/to/key.pem", `{"sub": "..."}`, "5m", "optional", "Authorization: Bearer",
+			), 0},
 		},
 		0,
 	}
 }
 
+func makeLabeledFields(placeholders ...string) []textinput.Model {
+	var fields []textinput.Model
+	for _, placeholder := range placeholders {
+		field := makeInputField("", placeholder)
+		field.Width = labeledFieldWidth
+		fields = append(fields, field)
+	}
+	return fields
+}
+
 type bodyType struct {
 	type_ string
 	value string
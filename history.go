@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+const historyMaxLines = 15
+
+// statusClasses are cycled by the status filter of the history browser
+var statusClasses = []string{"", "2xx", "3xx", "4xx", "5xx"}
+
+// historyEntry is a response of a request, trashed requests keep theirs
+// until the next start.
+type historyEntry struct {
+	request *DBRequest
+	index   int
+	trashed bool
+}
+
+func (e historyEntry) response() DBResponse {
+	return e.request.Responses[e.index]
+}
+
+// historyBrowser lists the responses of all the requests, the most recent
+// first. The input filters by "host:name" and any other text, the status
+// class and the method are cycled by their bindings.
+type historyBrowser struct {
+	listOverlay
+	entries []historyEntry
+	status  int // index in statusClasses
+	method  int // 0 is any, then the methods of the method field
+}
+
+func makeHistoryBrowser() historyBrowser {
+	return historyBrowser{listOverlay: makeListOverlay("host:example.com or any text...")}
+}
+
+// responseHost is the host of the URL sent, the URL may lack the scheme
+func responseHost(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func (h *historyBrowser) filter(m model) {
+	var host string
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(h.input.Value())) {
+		if value, ok := strings.CutPrefix(word, "host:"); ok {
+			host = value
+			continue
+		}
+		words = append(words, word)
+	}
+	method := ""
+	if h.method > 0 {
+		method = m.method.options[h.method-1]
+	}
+	matches := func(res DBResponse, name string) bool {
+		if h.status > 0 && (res.Status == "" || res.Status[0] != statusClasses[h.status][0]) {
+			return false
+		}
+		if method != "" && res.RequestMethod != method {
+			return false
+		}
+		if host != "" && !strings.Contains(strings.ToLower(responseHost(res.RequestUrl)), host) {
+			return false
+		}
+		text := strings.ToLower(strings.Join([]string{name, res.RequestMethod, res.RequestUrl, res.Status, res.Body}, "\n"))
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+		return true
+	}
+
+	h.entries = nil
+	add := func(r *DBRequest, trashed bool) {
+		for i, res := range r.Responses {
+			if matches(res, r.Name) {
+				h.entries = append(h.entries, historyEntry{r, i, trashed})
+			}
+		}
+	}
+	for _, r := range m.requests.items {
+		add(r, false)
+	}
+	for _, t := range m.trash {
+		add(t.request, true)
+	}
+	slices.SortStableFunc(h.entries, func(a, b historyEntry) int {
+		return b.response().ResponseAt.Compare(a.response().ResponseAt)
+	})
+	h.clamp(len(h.entries))
+}
+
+func openHistoryBrowser(m model) (model, tea.Cmd) {
+	cmd := m.historyBrowser.open(historyBrowserView)
+	m.historyBrowser.filter(m)
+	return m, cmd
+}
+
+// selectedResponse is the response under the history cursor, nil when the
+// request was never sent.
+func selectedResponse(m *model) *DBResponse {
+	if len(m.requests.items) == 0 {
+		return nil
+	}
+	responses := m.requests.items[m.requests.cursor].Responses
+	if m.history.cursor < 0 || m.history.cursor >= len(responses) {
+		return nil
+	}
+	return &responses[m.history.cursor]
+}
+
+// requestFromResponse is a new request with what was sent for the response
+func requestFromResponse(m model, res DBResponse) *DBRequest {
+	req := newRequest(m)
+	req.Name = res.RequestMethod + " " + res.RequestUrl
+	req.Method, req.Url = res.RequestMethod, res.RequestUrl
+	if res.Request != nil {
+		restoreSnapshot(req, res.Request)
+	}
+	return req
+}
+
+// jumpToEntry selects the response in the history of its request, the
+// request of a trashed one is gone so a new request is made from it.
+func jumpToEntry(m *model, e historyEntry) {
+	if e.trashed {
+		addRequest(m, requestFromResponse(*m, e.response()))
+		applyResponse(&m.tabState, e.response())
+		view = requestsView
+		return
+	}
+	m.requests.cursor = slices.Index(m.requests.items, e.request)
+	showRequest(m, e.request)
+	applyResponse(&m.tabState, e.response())
+	m.history.cursor = e.index
+	view = historyOptionsView
+}
+
+func updateHistoryBrowser(m model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, keys.Back) {
+		m.historyBrowser.close(m.historyBrowser.lastView)
+		return m, nil
+	}
+	h := &m.historyBrowser
+	if h.move(msg, len(h.entries)) {
+		return m, nil
+	}
+	switch {
+	case key.Matches(msg, keys.StatusFilter):
+		h.status = (h.status + 1) % len(statusClasses)
+		h.filter(m)
+		return m, nil
+	case key.Matches(msg, keys.MethodFilter):
+		h.method = (h.method + 1) % (len(m.method.options) + 1)
+		h.filter(m)
+		return m, nil
+	case key.Matches(msg, keys.Select):
+		if len(h.entries) == 0 {
+			return m, nil
+		}
+		e := h.entries[h.cursor]
+		h.close(requestsView)
+		jumpToEntry(&m, e)
+		return m, nil
+	}
+	var cmd tea.Cmd
+	h.input, cmd = h.input.Update(msg)
+	h.filter(m)
+	return m, cmd
+}
+
+func renderHistoryBrowser(m model) string {
+	h := m.historyBrowser
+	faint := lg.NewStyle().Foreground(placeHolderColor)
+	status, method := "any", "any"
+	if h.status > 0 {
+		status = statusClasses[h.status]
+	}
+	if h.method > 0 {
+		method = m.method.options[h.method-1]
+	}
+	filters := faint.Render(fmt.Sprintf("status: %s (%s)  method: %s (%s)", status, keys.StatusFilter.Help().Key, method, keys.MethodFilter.Help().Key))
+	lines := []string{lg.NewStyle().Bold(true).Render("History"), h.input.View(), filters, ""}
+	if len(h.entries) == 0 {
+		lines = append(lines, faint.Render("  no responses match"))
+	}
+	start, end := h.window(len(h.entries), historyMaxLines)
+	for i := start; i < end; i++ {
+		e := h.entries[i]
+		res := e.response()
+		at := faint.Render(res.ResponseAt.Format("Jan 02 15:04"))
+		line := h.mark(i) + at + " " + getStatusStyle(res.Status).Render(res.Status) + " " +
+			coloredMethod(res.RequestMethod) + " " + res.RequestUrl + " " + faint.Render(res.Duration+" "+res.Size)
+		lines = append(lines, lg.NewStyle().MaxWidth(rightPanelWidth).Render(line))
+	}
+	if len(h.entries) > 0 {
+		e := h.entries[h.cursor]
+		request := "request: " + e.request.Name
+		if e.trashed {
+			request += " (deleted, a new request is made from it)"
+		}
+		lines = append(lines, "", faint.Render(fmt.Sprintf("%d responses  %s", len(h.entries), request)))
+	}
+	lines = append(lines, "", renderOverlayHelp(historyBrowserView))
+	return secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(strings.Join(lines, "\n"))
+}
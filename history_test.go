@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// the history of a request never sent can't be opened, nor acted on
+func TestHistoryOptionsEmpty(t *testing.T) {
+	m := newTestModel(t)
+	view, mode = historyView, normal
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view != historyView {
+		t.Fatalf("the empty history was opened")
+	}
+	view = historyOptionsView
+	for _, msg := range []tea.KeyMsg{runes("p"), runes("d"), runes("c"), runes("r"), runes("j"), tea.KeyMsg{Type: tea.KeyEnter}} {
+		m = press(t, m, msg)
+		view = historyOptionsView
+	}
+	if n := len(m.requests.items); n != 1 {
+		t.Errorf("%d requests, want the one of the test", n)
+	}
+}
+
+func TestRequestFromResponse(t *testing.T) {
+	m := newTestModel(t)
+	res := DBResponse{RequestMethod: "POST", RequestUrl: "https://api.example.com/orders?page=2", Request: &RequestSnapshot{
+		Method:   "POST",
+		Url:      "https://api.example.com/orders",
+		Query:    []NameValue{{"page", "2"}},
+		Headers:  []NameValue{{"X-Tenant", "acme"}, {"Authorization", redacted}},
+		BodyType: "Json",
+		Body:     `{"amount": 10}`,
+		AuthType: "Bearer Token",
+	}}
+	r := requestFromResponse(m, res)
+	if r.Name != "POST https://api.example.com/orders?page=2" || r.Method != "POST" || r.Url != "https://api.example.com/orders" {
+		t.Errorf("request %q: %s %s", r.Name, r.Method, r.Url)
+	}
+	if len(r.Query) != 1 || r.Query[0] != (NameValue{"page", "2"}) {
+		t.Errorf("query = %v", r.Query)
+	}
+	// nothing to take the redacted value from in a new request
+	if len(r.Headers) != 2 || r.Headers[0] != (NameValue{"X-Tenant", "acme"}) || r.Headers[1].Value != redacted {
+		t.Errorf("headers = %v", r.Headers)
+	}
+	if body := r.Body.Types[r.Body.Selected]; body.Name != "Json" || body.Value != `{"amount": 10}` {
+		t.Errorf("body = %s %q", body.Name, body.Value)
+	}
+	if auth := r.Auth.Types[r.Auth.Selected]; auth.Type != "Bearer Token" {
+		t.Errorf("auth = %s", auth.Type)
+	}
+	if r.ID != 0 {
+		t.Errorf("the request was saved")
+	}
+
+	// responses from before the snapshots only have the method and the URL
+	r = requestFromResponse(m, DBResponse{RequestMethod: "DELETE", RequestUrl: "https://api.example.com/orders/1"})
+	if r.Method != "DELETE" || r.Url != "https://api.example.com/orders/1" || r.Body.Selected != 0 || r.Auth.Selected != 0 || len(r.Headers) != 0 {
+		t.Errorf("legacy response: %s %s, body %d, auth %d, headers %v", r.Method, r.Url, r.Body.Selected, r.Auth.Selected, r.Headers)
+	}
+}
+
+// restoreLegacy restores res, a response saved without a snapshot, in a tab
+// emptied first.
+func restoreLegacy(t *testing.T, m model, res DBResponse) model {
+	t.Helper()
+	r := m.tabs[m.activeTab].request
+	setUIRequest(&m, newRequest(m))
+	r.Responses = []DBResponse{res}
+	m.history.cursor = 0
+	view, mode = historyOptionsView, normal
+	return press(t, m, runes("r"))
+}
+
+func TestRestoreLegacyResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	m := newTestModel(t)
+	m.method.cursor = slices.Index(m.method.options, "POST")
+	m.url.SetValue(server.URL + "/orders?page=2")
+	m.queryParams.fields[0].SetValue("sort")
+	m.queryParams.fields[1].SetValue("desc")
+	m.headers.fields[0].SetValue("X-Tenant")
+	m.headers.fields[1].SetValue("acme")
+	m.body.cursor = 1
+	m.body.field.SetValue(`{"amount": 10}`)
+	m.auth.cursor = tokenAuth
+	m.auth.options[tokenAuth].fields[0].SetValue("Bearer")
+	m.auth.options[tokenAuth].fields[1].SetValue("secret-token")
+	msg, ok := sendRequest(m)().(response)
+	if !ok {
+		t.Fatal("sendRequest failed")
+	}
+
+	// saved before the snapshots, the raw capture has what was sent
+	m = restoreLegacy(t, m, DBResponse{RequestMethod: "POST", RequestUrl: server.URL + "/orders?page=2", Raw: msg.raw})
+	if view != historyView {
+		t.Errorf("view = %d, want the history", view)
+	}
+	if method := m.method.options[m.method.cursor]; method != "POST" || m.url.Value() != server.URL+"/orders?page=2" {
+		t.Errorf("restored %s %s", method, m.url.Value())
+	}
+	if query := nameValues(m.queryParams.fields); !slices.Equal(query, []NameValue{{"sort", "desc"}}) {
+		t.Errorf("query = %v", query)
+	}
+	// the Authorization of the auth is left out
+	if headers := nameValues(m.headers.fields); !slices.Equal(headers, []NameValue{{"X-Tenant", "acme"}}) {
+		t.Errorf("headers = %v", headers)
+	}
+	if m.body.options[m.body.cursor].type_ != "Json" || m.body.field.Value() != `{"amount": 10}` {
+		t.Errorf("body = %s %q", m.body.options[m.body.cursor].type_, m.body.field.Value())
+	}
+
+	// older ones only kept the method and the URL, the rest of the tab stays
+	m.headers.fields[0].SetValue("X-Kept")
+	m.headers.fields[1].SetValue("1")
+	r := m.tabs[m.activeTab].request
+	r.Responses = []DBResponse{{RequestMethod: "DELETE", RequestUrl: "https://api.example.com/orders/1"}}
+	m.history.cursor = 0
+	view = historyOptionsView
+	m = press(t, m, runes("r"))
+	if method := m.method.options[m.method.cursor]; method != "DELETE" || m.url.Value() != "https://api.example.com/orders/1" {
+		t.Errorf("restored %s %s", method, m.url.Value())
+	}
+	if headers := nameValues(m.headers.fields); !slices.Equal(headers, []NameValue{{"X-Kept", "1"}}) {
+		t.Errorf("headers = %v, want the ones of the tab", headers)
+	}
+}
+
+func TestHistoryHintRemapped(t *testing.T) {
+	m := newTestModel(t)
+	remapKeys(t, `{"restore": ["ctrl+r"], "delete": ["x", "delete"]}`)
+	m.requests.items[m.requests.cursor].Responses = []DBResponse{{Status: "200 OK", RequestMethod: "GET", RequestUrl: "https://example.com"}}
+	view = historyOptionsView
+	hint := renderHistory(m)
+	for _, want := range []string{"restore (ctrl+r)", "copy as new request (c)", "pin (p)", "delete (x/delete)"} {
+		if !strings.Contains(hint, want) {
+			t.Errorf("the hint lacks %q:\n%s", want, hint)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+var hmacLabels = []string{
+	"Algorithm", "Secret", "Key ID", "Key ID header", "Signature header", "Timestamp header", "Encoding",
+}
+
+var hmacHashes = map[string]func() hash.Hash{"sha1": sha1.New, "sha256": sha256.New, "sha512": sha512.New}
+
+type hmacConfig struct {
+	hash                                          func() hash.Hash
+	secret, keyID                                 string
+	keyIDHeader, signatureHeader, timestampHeader string
+	base64                                        bool
+}
+
+func hmacConfigFrom(fields []textinput.Model) (hmacConfig, error) {
+	value := func(i int) string { return strings.TrimSpace(fields[i].Value()) }
+	algorithm := strings.ToLower(cmp.Or(value(0), "sha256"))
+	h, ok := hmacHashes[algorithm]
+	if !ok {
+		return hmacConfig{}, fmt.Errorf("unknown algorithm %q, use sha1, sha256 or sha512", algorithm)
+	}
+	encoding := strings.ToLower(cmp.Or(value(6), "hex"))
+	if encoding != "hex" && encoding != "base64" {
+		return hmacConfig{}, fmt.Errorf("unknown encoding %q, use hex or base64", encoding)
+	}
+	return hmacConfig{
+		hash:            h,
+		secret:          fields[1].Value(),
+		keyID:           value(2),
+		keyIDHeader:     cmp.Or(value(3), "X-Key-Id"),
+		signatureHeader: cmp.Or(value(4), "X-Signature"),
+		timestampHeader: cmp.Or(value(5), "X-Timestamp"),
+		base64:          encoding == "base64",
+	}, nil
+}
+
+// signHMAC signs the method, the path with the query, the unix timestamp and
+// the hex SHA-256 of the body, one per line. It returns the string to sign
+// for the trace logs.
+func signHMAC(req *http.Request, body string, c hmacConfig, now time.Time) string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	stringToSign := strings.Join([]string{req.Method, req.URL.RequestURI(), timestamp, sha256Hex(body)}, "\n")
+
+	mac := hmac.New(c.hash, []byte(c.secret))
+	mac.Write([]byte(stringToSign))
+	signature := hex.EncodeToString(mac.Sum(nil))
+	if c.base64 {
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	req.Header.Set(c.timestampHeader, timestamp)
+	if c.keyID != "" {
+		req.Header.Set(c.keyIDHeader, c.keyID)
+	}
+	req.Header.Set(c.signatureHeader, signature)
+	return stringToSign
+}
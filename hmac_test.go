@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+// textFields makes the fields of an auth type with the given values
+func textFields(values ...string) []textinput.Model {
+	var fields []textinput.Model
+	for _, value := range values {
+		field := textinput.New()
+		field.SetValue(value)
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// the signatures were computed with Python's hmac module
+func TestSignHMAC(t *testing.T) {
+	const body = `{"amount": 10}`
+	const stringToSign = "POST\n/v1/orders?a=1&b=2\n1700000000\n7f8b279ef4be0909382db4442750f8eb84f2874cf43c826a86acffb6dc28c84d"
+	tests := []struct {
+		fields    []string
+		want      string
+		signature string
+		keyID     string
+	}{
+		{[]string{"", "topsecret", "", "", "", "", ""},
+			"daf54ef376c3239e85c3e8c4176630d07088aaaa755d046427aa9d305e0383f4", "X-Signature", ""},
+		{[]string{"SHA512", "topsecret", "key-1", "X-Client", "Signature", "Date-Unix", "base64"},
+			"16YmtNcY97RSC9HV8v0Rn7JYtJtJv82A/s/AvYGVy8TVZloxkV7fc0g2mleZPqSxuaizRLnHxUkjpScCNu/A+g==", "Signature", "X-Client"},
+		{[]string{"sha1", "topsecret", "", "", "", "", "hex"},
+			"8cfbc5fcba85d7f593464eb02909f0809c3a0ffd", "X-Signature", ""},
+	}
+	for _, tt := range tests {
+		config, err := hmacConfigFrom(textFields(tt.fields...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("POST", "https://api.example.com/v1/orders?a=1&b=2", strings.NewReader(body))
+		if got := signHMAC(req, body, config, time.Unix(1700000000, 0)); got != stringToSign {
+			t.Errorf("string to sign = %q, want %q", got, stringToSign)
+		}
+		if got := req.Header.Get(tt.signature); got != tt.want {
+			t.Errorf("%s (%v) = %s, want %s", tt.signature, tt.fields, got, tt.want)
+		}
+		if tt.keyID != "" && req.Header.Get(tt.keyID) != "key-1" {
+			t.Errorf("%s = %q, want key-1", tt.keyID, req.Header.Get(tt.keyID))
+		}
+	}
+
+	for _, fields := range [][]string{{"md5", "", "", "", "", "", ""}, {"", "", "", "", "", "", "base32"}} {
+		if _, err := hmacConfigFrom(textFields(fields...)); err == nil {
+			t.Errorf("hmacConfigFrom(%q) accepted it", fields)
+		}
+	}
+}
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptrace"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/icholy/digest"
 )
@@ -18,11 +21,20 @@ type (
 		body          string
 		status        string
 		headers       []NameValue
-		cookies       []NameValue
+		cookies       []ResponseCookie
 		contentLength string
 		duration      string
 		responseAt    time.Time
 		traceLogs     string
+		timing        Timing
+		raw           string
+		sent          *RequestSnapshot
+		request       *DBRequest
+		// what was sent, the request may have unsaved changes
+		method, url string
+		// the OAuth 2.0 token used, if any
+		tokenKey string
+		token    oauthToken
 	}
 	errMsg struct{ err error }
 )
@@ -31,19 +43,28 @@ func (e errMsg) Error() string { return e.err.Error() }
 
 func sendRequest(m model) tea.Cmd {
 	return func() tea.Msg {
-		c := &http.Client{Timeout: 10 * time.Second}
+		c := &http.Client{Timeout: 10 * time.Second, Transport: wireTransport}
+		if m.jar != nil {
+			c.Jar = m.jar
+		}
 		req, err := http.NewRequest(
 			m.method.options[m.method.cursor],
 			m.url.Value(),
 			strings.NewReader(m.body.field.Value()),
 		)
+		if err != nil {
+			return errMsg{err}
+		}
 
-		traceLogs := ""
-		trace := getTrace(&traceLogs)
-		ctx := httptrace.WithClientTrace(req.Context(), trace)
+		trace := newTraceRecorder()
+		ctx := httptrace.WithClientTrace(req.Context(), trace.clientTrace())
 		req = req.WithContext(ctx)
 
 		// auth
+		var (
+			tokenKey string
+			token    oauthToken
+		)
 		auth := m.auth.options[m.auth.cursor]
 		switch m.auth.cursor {
 		case noAuth:
@@ -52,11 +73,38 @@ func sendRequest(m model) tea.Cmd {
 			req.SetBasicAuth(username, password)
 		case digestAuth:
 			username, password := auth.fields[0].Value(), auth.fields[1].Value()
-			c.Transport = &digest.Transport{Username: username, Password: password}
+			c.Transport = &digest.Transport{Username: username, Password: password, Transport: wireTransport}
 		case tokenAuth:
 			req.Header.Add("Authorization", auth.fields[0].Value()+" "+auth.fields[1].Value())
 		case customAuth:
 			req.Header.Add("Authorization", auth.fields[0].Value())
+		case apiKeyAuth:
+			if name, value, inQuery := apiKey(auth.fields); !inQuery {
+				req.Header.Set(name, value)
+			}
+		case jwtAuth:
+			name, value, claims, err := jwtHeader(auth.fields, time.Now())
+			if err != nil {
+				return errMsg{fmt.Errorf("JWT: %w", err)}
+			}
+			req.Header.Set(name, value)
+			trace.notef("JWT claims:\n%s", claims)
+		case awsAuth, hmacAuth:
+			// signed below, once the request is final
+		case oauth2Auth:
+			config := oauthConfigFrom(auth.fields)
+			// not the context of the request, the token endpoint isn't part of its trace
+			token, err = oauthAccessToken(context.Background(), m.db, config)
+			if err != nil {
+				return errMsg{fmt.Errorf("OAuth 2.0: %w", err)}
+			}
+			tokenKey = config.key()
+			req.Header.Set("Authorization", token.header())
+		}
+		// what the auth sets is kept out of the history
+		var authHeaders []string
+		for name := range req.Header {
+			authHeaders = append(authHeaders, name)
 		}
 
 		// headers
@@ -77,8 +125,45 @@ func sendRequest(m model) tea.Cmd {
 				q.Add(name, value)
 			}
 		}
+		if m.auth.cursor == apiKeyAuth {
+			if name, value, inQuery := apiKey(auth.fields); inQuery {
+				q.Set(name, value)
+			}
+		}
+		req.URL.RawQuery = q.Encode()
 
-		start := time.Now()
+		// the signatures add their headers or change the ones of the request
+		unsigned := req.Header.Clone()
+		switch m.auth.cursor {
+		case awsAuth:
+			canonical, stringToSign := signSigV4(req, m.body.field.Value(), awsCredentialsFrom(auth.fields), time.Now())
+			// the logs are stored with the response, unlike the auth they are not encrypted
+			if token := awsCredentialsFrom(auth.fields).sessionToken; token != "" {
+				canonical = strings.ReplaceAll(canonical, token, "<redacted>")
+			}
+			trace.notef("SigV4 canonical request:\n%s\n\nSigV4 string to sign:\n%s", canonical, stringToSign)
+		case hmacAuth:
+			config, err := hmacConfigFrom(auth.fields)
+			if err != nil {
+				return errMsg{fmt.Errorf("HMAC: %w", err)}
+			}
+			stringToSign := signHMAC(req, m.body.field.Value(), config, time.Now())
+			trace.notef("HMAC string to sign:\n%s", stringToSign)
+		}
+		for name, values := range req.Header {
+			if !slices.Equal(unsigned[name], values) {
+				authHeaders = append(authHeaders, name)
+			}
+		}
+
+		redact := newRedactor(authHeaders, authSecrets(m, token))
+		snapshot := requestSnapshot(m, req, redact)
+
+		// after the auth, the OAuth token requests aren't part of it
+		wire := &wireCapture{}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), wire.clientTrace()))
+
+		start := trace.begin()
 		res, err := c.Do(req)
 		if err != nil {
 			return errMsg{err}
@@ -100,57 +185,35 @@ func sendRequest(m model) tea.Cmd {
 		for name, values := range res.Header {
 			headers = append(headers, NameValue{name, strings.Join(values, ",")})
 		}
-		var cookies []NameValue
-		for _, cookie := range res.Cookies() {
-			cookies = append(cookies, NameValue{cookie.Name, cookie.Value})
-		}
 
 		return response{
 			string(body),
 			res.Status,
 			headers,
-			cookies,
+			responseCookies(res),
 			length,
 			duration.Round(time.Millisecond).String(),
 			stop,
-			traceLogs,
+			trace.logs(),
+			trace.timing(stop),
+			redact.lines(wire.raw(req, res, body)),
+			snapshot,
+			m.requests.items[m.requests.cursor],
+			req.Method,
+			m.url.Value(),
+			tokenKey,
+			token,
 		}
 	}
 }
 
-func getTrace(b *string) *httptrace.ClientTrace {
-	var start time.Time
-
-	t0 := time.Now()
-	return &httptrace.ClientTrace{
-		GetConn: func(hostPort string) {
-			start = time.Now()
-			*b += fmt.Sprintf("GetConn(%s) %d ms\n", hostPort, start.Sub(t0).Milliseconds())
-		},
-		DNSStart: func(info httptrace.DNSStartInfo) {
-			start = time.Now()
-			*b += fmt.Sprintf("DNSStart(%+v) %d ms\n", info, start.Sub(t0).Milliseconds())
-		},
-		DNSDone: func(info httptrace.DNSDoneInfo) {
-			start = time.Now()
-			*b += fmt.Sprintf("DNSDone(%+v) %d ms\n", info, start.Sub(t0).Milliseconds())
-		},
-		ConnectStart: func(network, addr string) {
-			*b += fmt.Sprintf("ConnectStart(%s, %s)\n", network, addr)
-		},
-		ConnectDone: func(network, addr string, err error) {
-			*b += fmt.Sprintf("ConnectDone(%s, %s, %v)\n", network, addr, err)
-		},
-		GotConn: func(info httptrace.GotConnInfo) {
-			start = time.Now()
-			*b += fmt.Sprintf("GotConn(%+v) %d ms\n", info, start.Sub(t0).Milliseconds())
-		},
-		GotFirstResponseByte: func() {
-			start = time.Now()
-			*b += fmt.Sprintf("GotFirstResponseByte %d ms\n", start.Sub(t0).Milliseconds())
-		},
-		PutIdleConn: func(err error) {
-			*b += fmt.Sprintf("PutIdleConn(%+v)\n", err)
-		},
+// apiKey returns the name and value of the API Key auth type, sent in a
+// header unless the location is query.
+func apiKey(fields []textinput.Model) (name, value string, inQuery bool) {
+	name = strings.TrimSpace(fields[0].Value())
+	if name == "" {
+		name = fields[0].Placeholder
 	}
+	inQuery = strings.EqualFold(strings.TrimSpace(fields[2].Value()), "query")
+	return name, fields[1].Value(), inQuery
 }
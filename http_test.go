@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestModel(t *testing.T) model {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := initialModel(newTestStore(t))
+	addRequest(&m, newRequest(m))
+	return m
+}
+
+// the headers of the signatures are kept out of the history
+func TestSignedHeadersRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		auth    int
+		fields  map[int]string
+		headers []string
+	}{
+		{hmacAuth, map[int]string{1: "topsecret", 2: "key-1"}, []string{"X-Signature", "X-Key-Id", "X-Timestamp"}},
+		{awsAuth, map[int]string{awsAccessKey: "AKIDEXAMPLE", awsSecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", awsSessionToken: "session-token"},
+			[]string{"Authorization", "X-Amz-Date", "X-Amz-Security-Token"}},
+	}
+	for _, tt := range tests {
+		m := newTestModel(t)
+		m.url.SetValue(server.URL + "/orders")
+		m.headers.fields[0].SetValue("X-Request")
+		m.headers.fields[1].SetValue("kept")
+		m.auth.cursor = tt.auth
+		for i, value := range tt.fields {
+			m.auth.options[tt.auth].fields[i].SetValue(value)
+		}
+		msg, ok := sendRequest(m)().(response)
+		if !ok {
+			t.Fatalf("sendRequest with %s failed", m.auth.options[tt.auth].name)
+		}
+		sent := map[string]string{}
+		for _, header := range msg.sent.Sent {
+			sent[header.Name] = header.Value
+		}
+		for _, name := range tt.headers {
+			if sent[name] != redacted {
+				t.Errorf("%s: the snapshot kept %s: %q", m.auth.options[tt.auth].name, name, sent[name])
+			}
+			if !strings.Contains(msg.raw, name+": "+redacted) {
+				t.Errorf("%s: the raw capture kept %s", m.auth.options[tt.auth].name, name)
+			}
+		}
+		if sent["X-Request"] != "kept" {
+			t.Errorf("%s: the header of the request was redacted: %q", m.auth.options[tt.auth].name, sent["X-Request"])
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+var jwtLabels = []string{"Algorithm", "Key", "Claims", "Expires in", "Key ID", "Header"}
+
+var jwtHashes = map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+
+// jwtHeader generates the token and returns the header to send it in, the
+// header field reads "Name: prefix" and defaults to "Authorization: Bearer".
+// The claims also go to the trace logs.
+func jwtHeader(fields []textinput.Model, now time.Time) (name, value, claims string, err error) {
+	token, claims, err := generateJWT(fields, now)
+	if err != nil {
+		return "", "", "", err
+	}
+	name, prefix, _ := strings.Cut(cmp.Or(strings.TrimSpace(fields[5].Value()), "Authorization: Bearer"), ":")
+	value = strings.TrimSpace(strings.TrimSpace(prefix) + " " + token)
+	return strings.TrimSpace(name), value, claims, nil
+}
+
+// generateJWT signs the claims of the fields, iat and exp are filled in
+// unless the claims set them.
+func generateJWT(fields []textinput.Model, now time.Time) (token, claimsJSON string, err error) {
+	value := func(i int) string { return strings.TrimSpace(fields[i].Value()) }
+	alg := strings.ToUpper(cmp.Or(value(0), "HS256"))
+
+	claims := map[string]any{}
+	if value(2) != "" {
+		decoder := json.NewDecoder(strings.NewReader(value(2)))
+		decoder.UseNumber() // keep big numbers as they are
+		if err := decoder.Decode(&claims); err != nil {
+			return "", "", fmt.Errorf("invalid claims: %w", err)
+		}
+	}
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now.Unix()
+	}
+	if _, ok := claims["exp"]; !ok {
+		expiresIn, err := time.ParseDuration(cmp.Or(value(3), "5m"))
+		if err != nil {
+			return "", "", fmt.Errorf("invalid expiry: %w", err)
+		}
+		claims["exp"] = now.Add(expiresIn).Unix()
+	}
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if value(4) != "" {
+		header["kid"] = value(4)
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", "", err
+	}
+	key, err := jwtKey(fields[1].Value())
+	if err != nil {
+		return "", "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := jwtSign(alg, key, signingInput)
+	if err != nil {
+		return "", "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), string(payload), nil
+}
+
+// jwtKey returns the key as typed, or the content of the file for values
+// starting with @ since PEM keys don't fit a single line field.
+func jwtKey(value string) ([]byte, error) {
+	path, ok := strings.CutPrefix(strings.TrimSpace(value), "@")
+	if !ok {
+		return []byte(value), nil
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, rest)
+	}
+	return os.ReadFile(path)
+}
+
+func jwtSign(alg string, key []byte, signingInput string) ([]byte, error) {
+	h, ok := jwtHashes[alg[min(2, len(alg)):]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if strings.HasPrefix(alg, "HS") {
+		mac := hmac.New(h.New, key)
+		mac.Write([]byte(signingInput))
+		return mac.Sum(nil), nil
+	}
+
+	digest := h.New()
+	digest.Write([]byte(signingInput))
+	private, err := parsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if !strings.HasPrefix(alg, "RS") {
+			break
+		}
+		return rsa.SignPKCS1v15(rand.Reader, k, h, digest.Sum(nil))
+	case *ecdsa.PrivateKey:
+		if !strings.HasPrefix(alg, "ES") {
+			break
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		// JWS wants r and s concatenated, each padded to the key size
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	}
+	return nil, fmt.Errorf("the key can't sign %s", alg)
+}
+
+func parsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	if block == nil {
+		return nil, errors.New("the key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported private key %q", block.Type)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeJWT checks the header and returns the claims, the signed part and
+// the signature
+func decodeJWT(t *testing.T, token, alg string) (map[string]any, string, []byte) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %q doesn't have 3 parts", token)
+	}
+	var header map[string]string
+	var claims map[string]any
+	for i, v := range []any{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if header["alg"] != alg || header["typ"] != "JWT" {
+		t.Errorf("header = %v, want alg %s", header, alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims, parts[0] + "." + parts[1], signature
+}
+
+// jwtPayload is the JSON of the claims as signed
+func jwtPayload(t *testing.T, signed string) string {
+	t.Helper()
+	_, payload, _ := strings.Cut(signed, ".")
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return "@" + path
+}
+
+func TestJWTHS256(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	fields := textFields("", "secret", `{"sub": "42", "big": 12345678901234567890}`, "10m", "kid-1", "")
+	name, value, _, err := jwtHeader(fields, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, ok := strings.CutPrefix(value, "Bearer ")
+	if name != "Authorization" || !ok {
+		t.Fatalf("header = %s: %s, want Authorization: Bearer", name, value)
+	}
+	claims, signed, signature := decodeJWT(t, token, "HS256")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(signed))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		t.Error("the HS256 signature doesn't verify")
+	}
+	if claims["sub"] != "42" || claims["iat"] != float64(now.Unix()) || claims["exp"] != float64(now.Add(10*time.Minute).Unix()) {
+		t.Errorf("claims = %v", claims)
+	}
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if !strings.Contains(string(header), `"kid":"kid-1"`) {
+		t.Errorf("header %s doesn't have the key ID", header)
+	}
+	if !strings.Contains(jwtPayload(t, signed), `"big":12345678901234567890`) {
+		t.Error("the big number of the claims was changed")
+	}
+
+	// the claims set iat and exp themselves, the header field is custom
+	fields = textFields("HS256", "secret", `{"iat": 1, "exp": 2}`, "", "", "X-Token: JWT")
+	name, value, _, err = jwtHeader(fields, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, ok = strings.CutPrefix(value, "JWT ")
+	if name != "X-Token" || !ok {
+		t.Fatalf("header = %s: %s, want X-Token: JWT", name, value)
+	}
+	if claims, _, _ := decodeJWT(t, token, "HS256"); claims["iat"] != float64(1) || claims["exp"] != float64(2) {
+		t.Errorf("claims = %v, want the ones given", claims)
+	}
+}
+
+func TestJWTRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := generateJWT(textFields("RS256", writePEM(t, "PRIVATE KEY", der), "", "", "", ""), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, signed, signature := decodeJWT(t, token, "RS256")
+	digest := sha256.Sum256([]byte(signed))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("the RS256 signature doesn't verify: %v", err)
+	}
+
+	// an RSA key can't sign ES256
+	if _, _, err := generateJWT(textFields("ES256", writePEM(t, "PRIVATE KEY", der), "", "", "", ""), time.Now()); err == nil {
+		t.Error("ES256 with an RSA key succeeded")
+	}
+}
+
+func TestJWTES256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := generateJWT(textFields("ES256", writePEM(t, "EC PRIVATE KEY", der), "", "", "", ""), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, signed, signature := decodeJWT(t, token, "ES256")
+	if len(signature) != 64 {
+		t.Fatalf("signature is %d bytes, want r and s of 32 bytes", len(signature))
+	}
+	digest := sha256.Sum256([]byte(signed))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Error("the ES256 signature doesn't verify")
+	}
+}
+
+func TestJWTErrors(t *testing.T) {
+	for _, fields := range [][]string{
+		{"HS256", "secret", "not json", "", "", ""},
+		{"HS256", "secret", "", "soon", "", ""},
+		{"PS256", "secret", "", "", "", ""},
+		{"RS256", "not a pem key", "", "", "", ""},
+	} {
+		if _, _, err := generateJWT(textFields(fields...), time.Now()); err == nil {
+			t.Errorf("generateJWT(%q) succeeded", fields)
+		}
+	}
+}
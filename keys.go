@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type keyMap struct {
+	Palette       key.Binding
+	Save          key.Binding
+	Revert        key.Binding
+	Discard       key.Binding
+	Reveal        key.Binding
+	Cookies       key.Binding
+	ClearCookies  key.Binding
+	History       key.Binding
+	Search        key.Binding
+	Help          key.Binding
+	New           key.Binding
+	Delete        key.Binding
+	Copy          key.Binding
+	Undo          key.Binding
+	Restore       key.Binding
+	Inspect       key.Binding
+	Pin           key.Binding
+	Prune         key.Binding
+	Sort          key.Binding
+	Edit          key.Binding
+	NextTab       key.Binding
+	PrevTab       key.Binding
+	OpenTab       key.Binding
+	CloseTab      key.Binding
+	MoveDown      key.Binding
+	MoveUp        key.Binding
+	GrowRequest   key.Binding
+	ShrinkRequest key.Binding
+	Select        key.Binding
+	Down          key.Binding
+	Up            key.Binding
+	ListDown      key.Binding
+	ListUp        key.Binding
+	StatusFilter  key.Binding
+	MethodFilter  key.Binding
+	Next          key.Binding
+	Prev          key.Binding
+	Back          key.Binding
+	Done          key.Binding
+	Quit          key.Binding
+}
+
+var keys = defaultKeyMap()
+
+func defaultKeyMap() keyMap {
+	return keyMap{
+		Palette:       key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "palette")),
+		Help:          key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "toggle help")),
+		Save:          key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "save request")),
+		Revert:        key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "revert unsaved changes")),
+		Discard:       key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "quit without saving")),
+		Reveal:        key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "show/hide secrets")),
+		Cookies:       key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "cookie manager")),
+		ClearCookies:  key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "clear cookies of the domain")),
+		History:       key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "history of all requests")),
+		Search:        key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search requests and responses")),
+		New:           key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new")),
+		Delete:        key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
+		Copy:          key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+		Undo:          key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo delete")),
+		Restore:       key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "restore")),
+		Inspect:       key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "show/hide the request sent")),
+		Pin:           key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pin/unpin, pinned ones are never pruned")),
+		Prune:         key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "prune history")),
+		Sort:          key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort mode")),
+		Edit:          key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "open in editor")),
+		NextTab:       key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "next tab")),
+		PrevTab:       key.NewBinding(key.WithKeys("["), key.WithHelp("[", "previous tab")),
+		OpenTab:       key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open in new tab")),
+		CloseTab:      key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "close tab")),
+		MoveDown:      key.NewBinding(key.WithKeys("J"), key.WithHelp("J", "move down")),
+		MoveUp:        key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "move up")),
+		GrowRequest:   key.NewBinding(key.WithKeys("+"), key.WithHelp("+", "grow request pane")),
+		ShrinkRequest: key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "shrink request pane")),
+		Select:        key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
+		Down:          key.NewBinding(key.WithKeys("j"), key.WithHelp("j", "down")),
+		Up:            key.NewBinding(key.WithKeys("k"), key.WithHelp("k", "up")),
+		ListDown:      key.NewBinding(key.WithKeys("down", "ctrl+j"), key.WithHelp("down/ctrl+j", "next match")),
+		ListUp:        key.NewBinding(key.WithKeys("up", "ctrl+k"), key.WithHelp("up/ctrl+k", "previous match")),
+		StatusFilter:  key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "cycle the status")),
+		MethodFilter:  key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "cycle the method")),
+		Next:          key.NewBinding(key.WithKeys("tab", "l"), key.WithHelp("tab/l", "next")),
+		Prev:          key.NewBinding(key.WithKeys("shift+tab", "h"), key.WithHelp("shift+tab/h", "previous")),
+		Back:          key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+		Done:          key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "stop editing")),
+		Quit:          key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit")),
+	}
+}
+
+// bindings maps the names used in the config file to the bindings
+func (k *keyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"palette":       &k.Palette,
+		"help":          &k.Help,
+		"save":          &k.Save,
+		"revert":        &k.Revert,
+		"discard":       &k.Discard,
+		"reveal":        &k.Reveal,
+		"cookies":       &k.Cookies,
+		"history":       &k.History,
+		"search":        &k.Search,
+		"clearCookies":  &k.ClearCookies,
+		"new":           &k.New,
+		"delete":        &k.Delete,
+		"copy":          &k.Copy,
+		"undo":          &k.Undo,
+		"restore":       &k.Restore,
+		"inspect":       &k.Inspect,
+		"pin":           &k.Pin,
+		"prune":         &k.Prune,
+		"sort":          &k.Sort,
+		"edit":          &k.Edit,
+		"nextTab":       &k.NextTab,
+		"prevTab":       &k.PrevTab,
+		"openTab":       &k.OpenTab,
+		"closeTab":      &k.CloseTab,
+		"moveDown":      &k.MoveDown,
+		"moveUp":        &k.MoveUp,
+		"growRequest":   &k.GrowRequest,
+		"shrinkRequest": &k.ShrinkRequest,
+		"select":        &k.Select,
+		"down":          &k.Down,
+		"up":            &k.Up,
+		"listDown":      &k.ListDown,
+		"listUp":        &k.ListUp,
+		"statusFilter":  &k.StatusFilter,
+		"methodFilter":  &k.MethodFilter,
+		"next":          &k.Next,
+		"prev":          &k.Prev,
+		"back":          &k.Back,
+		"done":          &k.Done,
+		"quit":          &k.Quit,
+	}
+}
+
+// configPath returns the path of a file in the user config directory
+func configPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tuisomnium", name), nil
+}
+
+// loadKeyMap overrides the default bindings with the ones in keys.json of
+// the config directory, e.g. {"new": ["a"], "quit": ["q", "ctrl+c"]}.
+// A missing file is not an error.
+func loadKeyMap() (keyMap, error) {
+	k := defaultKeyMap()
+	path, err := configPath("keys.json")
+	if err != nil {
+		return k, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return k, nil
+	}
+	if err != nil {
+		return k, err
+	}
+	var remapped map[string][]string
+	if err := json.Unmarshal(data, &remapped); err != nil {
+		return k, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	bindings := k.bindings()
+	for name, bound := range remapped {
+		binding, ok := bindings[name]
+		if !ok {
+			return k, fmt.Errorf("unknown binding %q in %s", name, path)
+		}
+		if len(bound) == 0 {
+			return k, fmt.Errorf("binding %q in %s has no keys", name, path)
+		}
+		binding.SetKeys(bound...)
+		binding.SetHelp(strings.Join(bound, "/"), binding.Help().Desc)
+	}
+	return k, nil
+}
+
+// replay returns the key message of the first key of the binding, so
+// actions can be triggered from outside the keyboard (palette, mouse).
+func replay(b key.Binding) tea.KeyMsg {
+	switch k := b.Keys()[0]; k {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	default:
+		// the String() of runes is the runes, which is what bindings match
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+	}
+}
+
+// viewHelp lists the bindings that make sense in a view, it implements
+// help.KeyMap for the help overlay.
+type viewHelp [][]key.Binding
+
+func (h viewHelp) ShortHelp() []key.Binding {
+	var bindings []key.Binding
+	for _, column := range h {
+		bindings = append(bindings, column...)
+	}
+	return bindings
+}
+
+func (h viewHelp) FullHelp() [][]key.Binding { return h }
+
+func helpFor(v int) viewHelp {
+	global := []key.Binding{keys.Palette, keys.Save, keys.Revert, keys.Cookies, keys.History, keys.Search, keys.Prune, keys.NextTab, keys.PrevTab, keys.CloseTab, keys.GrowRequest, keys.ShrinkRequest, keys.Help, keys.Quit}
+	// the overlays take the keys before the global bindings
+	switch v {
+	case paletteView:
+		return viewHelp{{keys.ListUp, keys.ListDown, keys.Select, keys.Palette, keys.Back}}
+	case searchView:
+		return viewHelp{{keys.ListUp, keys.ListDown, keys.Select, keys.Back}}
+	case historyBrowserView:
+		return viewHelp{{keys.ListUp, keys.ListDown, keys.StatusFilter, keys.MethodFilter, keys.Select, keys.Back}}
+	case cookiesView:
+		if mode == insert {
+			return viewHelp{{keys.Select, keys.Done}}
+		}
+		return viewHelp{{keys.Up, keys.Down, keys.Select, keys.Delete, keys.ClearCookies, keys.Back}}
+	}
+	if mode == insert {
+		return viewHelp{{keys.Done, keys.Save}}
+	}
+	var local []key.Binding
+	switch v {
+	case requestsView:
+		local = []key.Binding{keys.Up, keys.Down, keys.Select, keys.New, keys.Copy, keys.Delete, keys.Undo, keys.MoveUp, keys.MoveDown, keys.Sort, keys.OpenTab, keys.Next}
+	case httpMethodView, bodyTypeView, authTypeView, historyView:
+		local = []key.Binding{keys.Select, keys.Up, keys.Down, keys.Next, keys.Prev}
+	case urlView, sendView:
+		local = []key.Binding{keys.Select, keys.Down, keys.Next, keys.Prev}
+	case queryView, headersView:
+		local = []key.Binding{keys.Select, keys.New, keys.Up, keys.Down, keys.Next, keys.Prev}
+	case methodOptionsView, bodyOptionsView, authOptionsView:
+		local = []key.Binding{keys.Up, keys.Down, keys.Select, keys.Back}
+	case historyOptionsView:
+		local = []key.Binding{keys.Up, keys.Down, keys.Select, keys.Restore, keys.Copy, keys.Inspect, keys.Pin, keys.Delete, keys.Back}
+	case bodyContentView:
+		local = []key.Binding{keys.Select, keys.Edit, keys.Up, keys.Down}
+	case authContentView:
+		local = []key.Binding{keys.Select, keys.Edit, keys.Reveal, keys.Up, keys.Down, keys.Next, keys.Prev, keys.Back}
+	case headersContentView:
+		local = []key.Binding{keys.Select, keys.Edit, keys.Up, keys.Down, keys.Next, keys.Prev, keys.Back}
+	case queryContentView:
+		local = []key.Binding{keys.Select, keys.Up, keys.Down, keys.Next, keys.Prev, keys.Back}
+	case responseView:
+		local = []key.Binding{keys.Select, keys.Edit, keys.Up, keys.Next, keys.Prev, keys.Back}
+	case responseRawView:
+		local = []key.Binding{keys.Select, keys.Up, keys.Prev, keys.Back}
+	case responseHeadersView, responseCookiesView, responseLogs:
+		local = []key.Binding{keys.Select, keys.Up, keys.Next, keys.Prev, keys.Back}
+	}
+	return viewHelp{local, global}
+}
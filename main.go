@@ -8,11 +8,24 @@ import (
 )
 
 func main() {
+	var err error
+	if keys, err = loadKeyMap(); err != nil {
+		log.Fatalf("unable to load key bindings: %v", err)
+	}
+	helpKeys = renderHelpKeys()
+	themeName, themes, err := loadTheme()
+	if err != nil {
+		log.Fatalf("unable to load theme: %v", err)
+	}
+	applyTheme(themeName, themes)
 	store := new(Store)
 	if err := store.Init(); err != nil {
 		log.Fatalf("unable to init store: %v", err)
 	}
-	p := tea.NewProgram(initialModel(store))
+	if err := unlockSecrets(store); err != nil {
+		log.Fatalf("unable to unlock secrets: %v", err)
+	}
+	p := tea.NewProgram(initialModel(store), tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error starting app: %v", err)
 	}
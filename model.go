@@ -1,8 +1,13 @@
 package main
 
 import (
+	"cmp"
 	"log"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/paginator"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -22,27 +27,119 @@ func (l *requestList) up() {
 	l.cursor = max(l.cursor-1, 0)
 }
 
+// swap moves the selected request by offset, returns false if it can't move
+func (l *requestList) swap(offset int) bool {
+	target := l.cursor + offset
+	if target < 0 || target >= len(l.items) {
+		return false
+	}
+	l.items[l.cursor], l.items[target] = l.items[target], l.items[l.cursor]
+	l.cursor = target
+	return true
+}
+
+const (
+	sortPosition int = iota
+	sortName
+	sortMethod
+	sortLastSent
+	sortCreated
+)
+
+var sortModes = []string{"custom", "name", "method", "last sent", "created"}
+
+func lastSent(r *DBRequest) time.Time {
+	var last time.Time
+	for _, res := range r.Responses {
+		if res.ResponseAt.After(last) {
+			last = res.ResponseAt
+		}
+	}
+	return last
+}
+
+// sortRequests orders the request list by the selected sort mode, keeping
+// the cursor on the same request.
+func sortRequests(m *model) {
+	if len(m.requests.items) == 0 {
+		return
+	}
+	selected := m.requests.items[m.requests.cursor]
+	var compare func(a, b *DBRequest) int
+	switch m.sortMode {
+	case sortPosition:
+		compare = func(a, b *DBRequest) int {
+			return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+		}
+	case sortName:
+		compare = func(a, b *DBRequest) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+	case sortMethod:
+		compare = func(a, b *DBRequest) int {
+			return slices.Index(m.method.options, a.Method) - slices.Index(m.method.options, b.Method)
+		}
+	case sortLastSent:
+		compare = func(a, b *DBRequest) int {
+			return lastSent(b).Compare(lastSent(a))
+		}
+	case sortCreated:
+		compare = func(a, b *DBRequest) int {
+			return cmp.Compare(a.ID, b.ID)
+		}
+	}
+	slices.SortStableFunc(m.requests.items, compare)
+	m.requests.cursor = slices.Index(m.requests.items, selected)
+	m.names = nil
+	for _, r := range m.requests.items {
+		m.names = append(m.names, makeInputField(r.Name, ""))
+	}
+}
+
+// trashedRequest remembers where a deleted request was so undo can put it back
+type trashedRequest struct {
+	request *DBRequest
+	index   int
+}
+
 type model struct {
 	db       *Store
 	requests requestList
+	trash    []trashedRequest
+	sortMode int
 
 	names []textinput.Model
 
-	method           selectField
-	url              textinput.Model
-	body             body
-	auth             auth
-	queryParams      inputFields
-	headers          inputFields
-	requestPaginator paginator.Model
-	requestContents  []string
-
-	history           selectField
-	resBody           viewport.Model
-	resHeaders        table.Model
-	resCookies        table.Model
-	resLogs           string
-	responsePaginator paginator.Model
+	// state of the active tab, the others keep theirs in tabs
+	tabState
+	tabs            []requestTab
+	activeTab       int
+	requestContents []string
+
+	// OAuth 2.0 tokens by config key, as shown in the auth pane
+	tokens        map[string]oauthToken
+	revealSecrets bool
+	// shows the request of the selected history entry
+	showSent bool
+
+	jar     *cookieJar
+	cookies cookieManager
+	// the responses of all the requests
+	historyBrowser historyBrowser
+	search         search
+
+	retention retention
+
+	palette  palette
+	help     help.Model
+	showHelp bool
+	// the unsaved changes prompt, before quitting or closing the active tab
+	confirmQuit  bool
+	confirmClose bool
+
+	width         int
+	height        int
+	requestHeight int
 
 	err error
 }
@@ -50,19 +147,46 @@ type model struct {
 func initialModel(store *Store) model {
 	url := makeInputField("", "https...")
 	m := model{
-		db:               store,
-		requests:         requestList{},
-		method:           makeSelectField("GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"),
-		url:              url,
-		body:             makeBody(),
-		auth:             makeAuth(),
-		resBody:          viewport.New(40, 10),
-		resLogs:          "",
-		requestContents:  []string{"", "", "", ""},
+		db:       store,
+		requests: requestList{},
+		tabState: tabState{
+			method:  makeSelectField("GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"),
+			url:     url,
+			body:    makeBody(),
+			auth:    makeAuth(),
+			resBody: viewport.New(40, 10),
+			resRaw:  viewport.New(40, 10),
+			resLogs: "",
+		},
+		requestContents: []string{"", "", "", ""},
+		palette:         makePalette(),
+		cookies:         makeCookieManager(),
+		historyBrowser:  makeHistoryBrowser(),
+		search:          makeSearch(),
+		help:            help.New(),
+		requestHeight:   10,
 	}
 
 	m = setupUI(m)
 
+	tokens, err := store.GetTokens()
+	if err != nil {
+		log.Fatalf("unable to get OAuth 2.0 tokens: %v", err)
+	}
+	m.tokens = tokens
+	if m.jar, err = newCookieJar(store); err != nil {
+		log.Fatalf("unable to load cookies: %v", err)
+	}
+	// prune before loading what would be pruned
+	if m.retention, err = loadRetention(); err != nil {
+		log.Fatalf("unable to load retention: %v", err)
+	}
+	if m.retention.set() {
+		if _, err := store.PruneResponses(m.retention); err != nil {
+			log.Fatalf("unable to prune history: %v", err)
+		}
+	}
+
 	requests, err := store.GetRequests()
 	if err != nil {
 		log.Fatalf("unable to get saved requests: %v", err)
@@ -72,7 +196,13 @@ func initialModel(store *Store) model {
 	}
 
 	setUIRequestNames(&m, requests)
-	setUIRequest(&m, &requests[0])
+	sortMode, err := store.GetSetting("sort", sortModes[sortPosition])
+	if err != nil {
+		log.Fatalf("unable to get sort setting: %v", err)
+	}
+	m.sortMode = max(slices.Index(sortModes, sortMode), 0)
+	sortRequests(&m)
+	openTab(&m, m.requests.items[m.requests.cursor])
 
 	return m
 }
@@ -91,8 +221,8 @@ func setupUI(m model) model {
 	t.KeyMap.PrevPage.SetEnabled(false)
 	t.Type = paginator.Dots
 	t.PerPage = 1
-	t.ActiveDot = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "235", Dark: "252"}).Background(primaryColor).Render("•")
-	t.InactiveDot = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "250", Dark: "238"}).Background(primaryColor).Render("•")
+	t.ActiveDot = lipgloss.NewStyle().Foreground(activeDotColor).Background(primaryColor).Render("•")
+	t.InactiveDot = lipgloss.NewStyle().Foreground(inactiveDotColor).Background(primaryColor).Render("•")
 	t.SetTotalPages(len(m.requestContents))
 	m.requestPaginator = t
 
@@ -101,9 +231,9 @@ func setupUI(m model) model {
 	p.KeyMap.PrevPage.SetEnabled(false)
 	p.Type = paginator.Dots
 	p.PerPage = 1
-	p.ActiveDot = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "235", Dark: "252"}).Render("•")
-	p.InactiveDot = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "250", Dark: "238"}).Render("•")
-	p.SetTotalPages(4)
+	p.ActiveDot = lipgloss.NewStyle().Foreground(activeDotColor).Render("•")
+	p.InactiveDot = lipgloss.NewStyle().Foreground(inactiveDotColor).Render("•")
+	p.SetTotalPages(5)
 	m.responsePaginator = p
 
 	// default query empty fields
@@ -117,6 +247,7 @@ func setupUI(m model) model {
 
 	m.resHeaders = makeTable()
 	m.resCookies = makeTable()
+	m.resCookies.SetColumns(cookieColumns(rightPanelWidth))
 
 	return m
 }
@@ -134,12 +265,12 @@ func makeTable() table.Model {
 	s := table.DefaultStyles()
 	s.Header = s.Header.
 		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("240")).
+		BorderForeground(tableBorderColor).
 		BorderBottom(true).
 		Bold(false)
 	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("57")).
+		Foreground(selectedTextColor).
+		Background(selectedColor).
 		Bold(false)
 	t.SetStyles(s)
 	return t
@@ -163,10 +294,9 @@ func setUIRequest(m *model, r *DBRequest) {
 	}
 
 	// populate saved auth
-	for i, type_ := range r.Auth.Types {
-		uiAuth := m.auth.options[i]
-		for j, field := range type_.Fields {
-			uiAuth.fields[j].SetValue(field.Value)
+	for i, option := range m.auth.options {
+		for j := range option.fields {
+			option.fields[j].SetValue(r.authValue(i, j))
 		}
 	}
 	m.auth.cursor = r.Auth.Selected
@@ -189,6 +319,15 @@ func setUIRequest(m *model, r *DBRequest) {
 	}
     m.headers.fields = append(m.headers.fields, makeInputField("", "header"), makeInputField("", "name"))
 
+	m.queryParams.cursor = 0
+	m.headers.cursor = 0
+	m.history.cursor = 0
+	m.resBody.SetContent("")
+	m.resHeaders.SetRows(nil)
+	m.resCookies.SetRows(nil)
+	m.resLogs = ""
+	m.resTiming = Timing{}
+	m.resRaw.SetContent("")
 	if len(r.Responses) > 0 {
 		r := &r.Responses[0]
 		m.resBody.SetContent(r.Body)
@@ -200,13 +339,11 @@ func setUIRequest(m *model, r *DBRequest) {
 		m.resHeaders.SetRows(res_headers)
 
 		// populate saved response cookies
-		res_cookies := []table.Row{}
-		for _, item := range r.Cookies {
-			res_cookies = append(res_cookies, table.Row{item.Name, item.Value})
-		}
-		m.resCookies.SetRows(res_cookies)
+		setCookieRows(&m.resCookies, r.Cookies)
 
 		// populate trace logs
 		m.resLogs = r.TraceLogs
+		m.resTiming = r.Timing
+		setRawContent(&m.resRaw, r.Raw)
 	}
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// zones that are not views, clicking them switches paginator pages or tabs
+const (
+	requestPagesZone int = -1 - iota
+	responsePagesZone
+	tabsZone
+)
+
+const wheelLines = 3
+
+// zone is a clickable area of the last rendered frame, index is the item
+// inside the view (request, option, field, history entry or page).
+type zone struct {
+	view, index int
+	x, y, w, h  int
+}
+
+// zones are registered by View and hit tested by Update
+var zones []zone
+
+func addZone(view, index, x, y, w, h int) {
+	zones = append(zones, zone{view, index, x, y, w, h})
+}
+
+// addZones registers rendered pieces laid out horizontally from x.
+func addZones(x, y, h int, views []int, pieces ...string) {
+	for i, piece := range pieces {
+		w := lg.Width(piece)
+		addZone(views[i], 0, x, y, w, h)
+		x += w
+	}
+}
+
+// addListZones registers one line per item of a vertical list.
+func addListZones(view, x, y, w, count int) {
+	for i := range count {
+		addZone(view, i, x, y+i, w, 1)
+	}
+}
+
+// addDotZones registers the dots of a paginator, one cell per page.
+func addDotZones(x, y, count, kind int) {
+	for i := range count {
+		addZone(kind, i, x+i, y, 1, 1)
+	}
+}
+
+// resetZones starts a new frame with the sidebar request names.
+func resetZones(m model) {
+	zones = nil
+	offset := max(0, m.requests.cursor-nameListStyle.GetHeight()+1)
+	top := lg.Height(helpKeys+renderSortMode(m)) + 1 // +1 for the border
+	for i := offset; i < min(len(m.names), offset+nameListStyle.GetHeight()); i++ {
+		addZone(requestsView, i, 0, top+i-offset, nameListStyle.GetWidth()+2, 1)
+	}
+}
+
+func addRequestContentZones(m model, x, y, h int) {
+	switch m.requestPaginator.Page {
+	case 0:
+		if m.body.cursor != 0 {
+			addZone(bodyContentView, 0, x, y, rightPanelWidth, h)
+		}
+	case 1:
+		// every auth field is a line plus its bottom border
+		auth := m.auth.options[m.auth.cursor]
+		offset, count := 0, len(auth.fields)
+		if _, ok := authLabels[m.auth.cursor]; ok {
+			offset, count = authFieldsShown(m)
+			if header := renderAuthHeader(m); header != "" {
+				y += lg.Height(header)
+			}
+		}
+		for i := offset; i < offset+count; i++ {
+			addZone(authContentView, i, x, y+(i-offset)*2, rightPanelWidth, 2)
+		}
+	case 2, 3:
+		fields, contentView := m.queryParams.fields, queryContentView
+		if m.requestPaginator.Page == 3 {
+			fields, contentView = m.headers.fields, headersContentView
+		}
+		// same widths as renderNameValueFields, name and value split by 3 spaces
+		w := requestPaginatorStyle.GetWidth()/2 - 20
+		for i := range fields {
+			fieldX := x
+			if i%2 != 0 {
+				fieldX += w + 3
+			}
+			addZone(contentView, i, fieldX, y+i/2*2, w, 2)
+		}
+	}
+}
+
+func zoneAt(x, y int) (zone, bool) {
+	for i := len(zones) - 1; i >= 0; i-- {
+		z := zones[i]
+		if x >= z.x && x < z.x+z.w && y >= z.y && y < z.y+z.h {
+			return z, true
+		}
+	}
+	return zone{}, false
+}
+
+func pressKey(m model, b key.Binding) (tea.Model, tea.Cmd) {
+	return m.Update(replay(b))
+}
+
+func updateMouse(m model, msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if view == paletteView || view == cookiesView || view == historyBrowserView || view == searchView || m.showHelp || msg.Action != tea.MouseActionPress || len(m.requests.items) == 0 {
+		return m, nil
+	}
+	z, ok := zoneAt(msg.X, msg.Y)
+	if !ok {
+		return m, nil
+	}
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		return scroll(m, z, msg.Button == tea.MouseButtonWheelUp), nil
+	case tea.MouseButtonLeft:
+	default:
+		return m, nil
+	}
+
+	// leave insert mode the same way esc does, so the edit is kept
+	if mode == insert {
+		next, _ := pressKey(m, keys.Done)
+		m = next.(model)
+		// blur focused tables and the response viewport
+		next, _ = pressKey(m, keys.Back)
+		m = next.(model)
+	}
+
+	switch z.view {
+	case requestsView:
+		view = requestsView
+		if z.index != m.requests.cursor {
+			m.requests.cursor = z.index
+			showRequest(&m, m.requests.items[m.requests.cursor])
+		}
+		return m, nil
+	case tabsZone:
+		switchTab(&m, z.index)
+		return m, nil
+	case requestPagesZone:
+		m.requestPaginator.Page = z.index
+		view = bodyTypeView + z.index
+		return m, nil
+	case responsePagesZone:
+		m.responsePaginator.Page = z.index
+		view = responseView + z.index
+		return m, nil
+	case methodOptionsView:
+		m.method.cursor = z.index
+	case bodyOptionsView:
+		m.body.cursor = z.index
+	case authOptionsView:
+		m.auth.cursor = z.index
+	case historyOptionsView:
+		m.history.cursor = z.index
+	case bodyTypeView, authTypeView, queryView, headersView:
+		m.requestPaginator.Page = z.view - bodyTypeView
+	case authContentView:
+		m.auth.options[m.auth.cursor].cursor = z.index
+	case queryContentView:
+		m.queryParams.cursor = z.index
+	case headersContentView:
+		m.headers.cursor = z.index
+	}
+
+	// a click focuses the zone, options, buttons and fields are also activated
+	focusedBefore := view == z.view
+	view = z.view
+	switch z.view {
+	case methodOptionsView, bodyOptionsView, authOptionsView, historyOptionsView, sendView,
+		urlView, bodyContentView, authContentView, queryContentView, headersContentView:
+		return pressKey(m, keys.Select)
+	case httpMethodView, bodyTypeView, authTypeView, historyView:
+		if focusedBefore {
+			return pressKey(m, keys.Select)
+		}
+	}
+	return m, nil
+}
+
+func scroll(m model, z zone, up bool) model {
+	switch z.view {
+	case requestsView:
+		if up {
+			m.requests.up()
+		} else {
+			m.requests.down()
+		}
+		showRequest(&m, m.requests.items[m.requests.cursor])
+	case responseView:
+		if up {
+			m.resBody.LineUp(wheelLines)
+		} else {
+			m.resBody.LineDown(wheelLines)
+		}
+	case responseRawView:
+		if up {
+			m.resRaw.LineUp(wheelLines)
+		} else {
+			m.resRaw.LineDown(wheelLines)
+		}
+	case responseHeadersView, responseCookiesView:
+		t := &m.resHeaders
+		if z.view == responseCookiesView {
+			t = &m.resCookies
+		}
+		if up {
+			t.MoveUp(1)
+		} else {
+			t.MoveDown(1)
+		}
+	case historyOptionsView:
+		if up {
+			m.history.cursor = max(m.history.cursor-1, 0)
+		} else {
+			m.history.cursor = min(m.history.cursor+1, len(m.requests.items[m.requests.cursor].Responses)-1)
+		}
+	}
+	return m
+}
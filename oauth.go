@@ -0,0 +1,336 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+// fields of the OAuth 2.0 auth type
+const (
+	oauthGrant int = iota
+	oauthTokenURL
+	oauthAuthURL
+	oauthRedirectURL
+	oauthClientID
+	oauthClientSecret
+	oauthScope
+	oauthUsername
+	oauthPassword
+	oauthRefreshToken
+)
+
+var oauthLabels = []string{
+	"Grant type", "Token URL", "Auth URL", "Redirect URL", "Client ID",
+	"Client secret", "Scope", "Username", "Password", "Refresh token",
+}
+
+const (
+	// tokens this close to their expiry are refreshed before sending
+	tokenExpiryMargin    = 30 * time.Second
+	authorizationTimeout = 2 * time.Minute
+	defaultRedirectURL   = "http://127.0.0.1:0/callback"
+)
+
+type oauthToken struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Scope        string
+	ExpiresAt    time.Time // zero if the server didn't tell
+}
+
+func (t oauthToken) valid() bool {
+	return t.AccessToken != "" && (t.ExpiresAt.IsZero() || time.Until(t.ExpiresAt) > tokenExpiryMargin)
+}
+
+func (t oauthToken) header() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+type oauthConfig struct {
+	grant, tokenURL, authURL, redirectURL string
+	clientID, clientSecret, scope         string
+	username, password, refreshToken      string
+}
+
+func oauthConfigFrom(fields []textinput.Model) oauthConfig {
+	value := func(i int) string { return strings.TrimSpace(fields[i].Value()) }
+	return oauthConfig{
+		grant:        cmp.Or(value(oauthGrant), "client_credentials"),
+		tokenURL:     value(oauthTokenURL),
+		authURL:      value(oauthAuthURL),
+		redirectURL:  cmp.Or(value(oauthRedirectURL), defaultRedirectURL),
+		clientID:     value(oauthClientID),
+		clientSecret: value(oauthClientSecret),
+		scope:        value(oauthScope),
+		username:     value(oauthUsername),
+		password:     value(oauthPassword),
+		refreshToken: value(oauthRefreshToken),
+	}
+}
+
+// key identifies the tokens of the config in the store, requests that
+// authenticate the same way share them. The credentials are in it hashed, a
+// token is never given for a secret or a password other than its own.
+func (c oauthConfig) key() string {
+	credentials := sha256.Sum256([]byte(strings.Join([]string{c.clientSecret, c.password, c.refreshToken}, "\n")))
+	return strings.Join([]string{c.grant, c.tokenURL, c.clientID, c.scope, c.username, hex.EncodeToString(credentials[:])}, "\n")
+}
+
+// oauthAccessToken returns the cached token of the config while it is valid,
+// refreshes it once expired and acquires a new one with the grant otherwise.
+func oauthAccessToken(ctx context.Context, store *Store, c oauthConfig) (oauthToken, error) {
+	cached, ok, err := store.GetToken(c.key())
+	if err != nil {
+		return oauthToken{}, err
+	}
+	if ok && cached.valid() {
+		return cached, nil
+	}
+	var token oauthToken
+	if cached.RefreshToken != "" {
+		token, err = refreshToken(ctx, c, cached.RefreshToken)
+	}
+	// the refresh token may have expired as well, start over with the grant
+	if cached.RefreshToken == "" || err != nil {
+		token, err = acquireToken(ctx, c)
+	}
+	if err != nil {
+		return oauthToken{}, err
+	}
+	return token, store.SaveToken(c.key(), token)
+}
+
+func acquireToken(ctx context.Context, c oauthConfig) (oauthToken, error) {
+	switch c.grant {
+	case "client_credentials":
+		return requestToken(ctx, c, url.Values{"grant_type": {"client_credentials"}, "scope": {c.scope}})
+	case "password":
+		return requestToken(ctx, c, url.Values{
+			"grant_type": {"password"}, "username": {c.username}, "password": {c.password}, "scope": {c.scope},
+		})
+	case "refresh_token":
+		if c.refreshToken == "" {
+			return oauthToken{}, errors.New("missing refresh token")
+		}
+		return refreshToken(ctx, c, c.refreshToken)
+	case "authorization_code":
+		return authorizeWithPKCE(ctx, c)
+	}
+	return oauthToken{}, fmt.Errorf("unknown grant type %q", c.grant)
+}
+
+func refreshToken(ctx context.Context, c oauthConfig, refresh string) (oauthToken, error) {
+	token, err := requestToken(ctx, c, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refresh}})
+	// servers don't have to rotate the refresh token
+	if err == nil && token.RefreshToken == "" {
+		token.RefreshToken = refresh
+	}
+	return token, err
+}
+
+// requestToken posts the form to the token endpoint, the client
+// authenticates with basic auth when it has a secret.
+func requestToken(ctx context.Context, c oauthConfig, form url.Values) (oauthToken, error) {
+	if c.tokenURL == "" {
+		return oauthToken{}, errors.New("missing token URL")
+	}
+	if form.Get("scope") == "" {
+		form.Del("scope")
+	}
+	if c.clientID != "" {
+		form.Set("client_id", c.clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oauthToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return oauthToken{}, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return oauthToken{}, err
+	}
+
+	var payload struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		RefreshToken     string      `json:"refresh_token"`
+		Scope            string      `json:"scope"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return oauthToken{}, fmt.Errorf("token endpoint replied %s: %.200s", res.Status, body)
+	}
+	if payload.Error != "" {
+		return oauthToken{}, fmt.Errorf("token endpoint replied %s: %s %s", res.Status, payload.Error, payload.ErrorDescription)
+	}
+	if res.StatusCode != http.StatusOK || payload.AccessToken == "" {
+		return oauthToken{}, fmt.Errorf("token endpoint replied %s without an access token", res.Status)
+	}
+
+	token := oauthToken{
+		AccessToken:  payload.AccessToken,
+		TokenType:    payload.TokenType,
+		RefreshToken: payload.RefreshToken,
+		Scope:        cmp.Or(payload.Scope, form.Get("scope")),
+	}
+	if seconds, err := payload.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// authorizeWithPKCE opens the authorization URL in the browser and waits
+// for the redirect on a loopback listener, port 0 picks a free port.
+func authorizeWithPKCE(ctx context.Context, c oauthConfig) (oauthToken, error) {
+	if c.authURL == "" {
+		return oauthToken{}, errors.New("missing auth URL")
+	}
+	verifier, state := randomString(32), randomString(16)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	redirect, err := url.Parse(c.redirectURL)
+	if err != nil {
+		return oauthToken{}, fmt.Errorf("invalid redirect URL: %w", err)
+	}
+	if redirect.Scheme != "http" {
+		return oauthToken{}, fmt.Errorf("the redirect URL %s must be http, the redirect is served on a loopback listener", c.redirectURL)
+	}
+	// without a port the browser is redirected to port 80
+	addr := net.JoinHostPort(redirect.Hostname(), cmp.Or(redirect.Port(), "80"))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return oauthToken{}, fmt.Errorf("unable to listen for the redirect on %s: %w", addr, err)
+	}
+	if redirect.Port() == "0" {
+		port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+		redirect.Host = net.JoinHostPort(redirect.Hostname(), port)
+	}
+	redirect.Path = cmp.Or(redirect.Path, "/")
+
+	authURL, err := url.Parse(c.authURL)
+	if err != nil {
+		listener.Close()
+		return oauthToken{}, fmt.Errorf("invalid auth URL: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", c.clientID)
+	q.Set("redirect_uri", redirect.String())
+	q.Set("state", state)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	if c.scope != "" {
+		q.Set("scope", c.scope)
+	}
+	authURL.RawQuery = q.Encode()
+
+	codes, errs := make(chan string, 1), make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != redirect.Path {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "Unexpected state, try again from the terminal.", http.StatusBadRequest)
+		case q.Get("error") != "":
+			fmt.Fprintln(w, "Authorization failed, you can close this window.")
+			select {
+			case errs <- fmt.Errorf("authorization failed: %s %s", q.Get("error"), q.Get("error_description")):
+			default:
+			}
+		default:
+			fmt.Fprintln(w, "Authorized, you can close this window and go back to the terminal.")
+			select {
+			case codes <- q.Get("code"):
+			default:
+			}
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err := openBrowser(authURL.String()); err != nil {
+		return oauthToken{}, fmt.Errorf("failed to open the browser: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, authorizationTimeout)
+	defer cancel()
+	select {
+	case code := <-codes:
+		return requestToken(ctx, c, url.Values{
+			"grant_type": {"authorization_code"}, "code": {code},
+			"redirect_uri": {redirect.String()}, "code_verifier": {verifier},
+		})
+	case err := <-errs:
+		return oauthToken{}, err
+	case <-ctx.Done():
+		return oauthToken{}, fmt.Errorf("no authorization within %s", authorizationTimeout)
+	}
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// openBrowser uses $BROWSER when set, the desktop default otherwise
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	if args := strings.Fields(os.Getenv("BROWSER")); len(args) > 0 {
+		cmd = exec.Command(args[0], append(args[1:], url)...)
+	} else {
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = exec.Command("open", url)
+		case "windows":
+			cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+		default:
+			cmd = exec.Command("xdg-open", url)
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
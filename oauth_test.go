@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenEndpoint stands in for the token endpoint of an authorization server,
+// it counts the requests by grant type.
+type tokenEndpoint struct {
+	*httptest.Server
+	grants map[string]*atomic.Int32
+	reply  func(w http.ResponseWriter, r *http.Request)
+}
+
+func newTokenEndpoint(t *testing.T) *tokenEndpoint {
+	e := &tokenEndpoint{grants: map[string]*atomic.Int32{}}
+	for _, grant := range []string{"client_credentials", "refresh_token", "password"} {
+		e.grants[grant] = new(atomic.Int32)
+	}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		r.ParseForm()
+		if count, ok := e.grants[r.PostForm.Get("grant_type")]; ok {
+			count.Add(1)
+		}
+		e.reply(w, r)
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+func (e *tokenEndpoint) count(grant string) int32 { return e.grants[grant].Load() }
+
+func TestOAuthClientCredentials(t *testing.T) {
+	e := newTokenEndpoint(t)
+	e.reply = func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "s3cret" || r.PostForm.Get("scope") != "read" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "abc", "token_type": "bearer", "expires_in": 3600}`)
+	}
+	store := newTestStore(t)
+	c := oauthConfig{grant: "client_credentials", tokenURL: e.URL, clientID: "client", clientSecret: "s3cret", scope: "read"}
+	token, err := oauthAccessToken(context.Background(), store, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.header() != "Bearer abc" || token.Scope != "read" || time.Until(token.ExpiresAt) < 59*time.Minute {
+		t.Errorf("token = %+v", token)
+	}
+	// the token is cached while valid
+	if _, err := oauthAccessToken(context.Background(), store, c); err != nil {
+		t.Fatal(err)
+	}
+	if n := e.count("client_credentials"); n != 1 {
+		t.Errorf("the token endpoint got %d requests, want 1", n)
+	}
+}
+
+func TestOAuthRefresh(t *testing.T) {
+	e := newTokenEndpoint(t)
+	e.reply = func(w http.ResponseWriter, r *http.Request) {
+		switch r.PostForm.Get("grant_type") {
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "r1" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "refresh token expired"}`)
+				return
+			}
+			// no refresh_token, the server doesn't rotate it
+			fmt.Fprint(w, `{"access_token": "refreshed", "expires_in": 3600}`)
+		default:
+			fmt.Fprint(w, `{"access_token": "new", "refresh_token": "r2", "expires_in": 3600}`)
+		}
+	}
+	c := oauthConfig{grant: "client_credentials", tokenURL: e.URL, clientID: "client"}
+
+	tests := []struct {
+		name      string
+		cached    oauthToken
+		want      string
+		refreshes int32
+		grants    int32
+	}{
+		{"valid", oauthToken{AccessToken: "cached", RefreshToken: "r1", ExpiresAt: time.Now().Add(time.Minute)}, "cached", 0, 0},
+		{"within the expiry margin", oauthToken{AccessToken: "cached", RefreshToken: "r1", ExpiresAt: time.Now().Add(tokenExpiryMargin / 2)}, "refreshed", 1, 0},
+		{"expired", oauthToken{AccessToken: "cached", RefreshToken: "r1", ExpiresAt: time.Now().Add(-time.Hour)}, "refreshed", 1, 0},
+		{"refresh token rejected", oauthToken{AccessToken: "cached", RefreshToken: "old", ExpiresAt: time.Now().Add(-time.Hour)}, "new", 1, 1},
+		{"no refresh token", oauthToken{AccessToken: "cached", ExpiresAt: time.Now().Add(-time.Hour)}, "new", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			if err := store.SaveToken(c.key(), tt.cached); err != nil {
+				t.Fatal(err)
+			}
+			refreshes, grants := e.count("refresh_token"), e.count("client_credentials")
+			token, err := oauthAccessToken(context.Background(), store, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != tt.want {
+				t.Errorf("access token = %q, want %q", token.AccessToken, tt.want)
+			}
+			if n := e.count("refresh_token") - refreshes; n != tt.refreshes {
+				t.Errorf("%d refreshes, want %d", n, tt.refreshes)
+			}
+			if n := e.count("client_credentials") - grants; n != tt.grants {
+				t.Errorf("%d client_credentials grants, want %d", n, tt.grants)
+			}
+			if tt.want == "refreshed" && token.RefreshToken != "r1" {
+				t.Errorf("refresh token = %q, want r1 kept", token.RefreshToken)
+			}
+			saved, _, _ := store.GetToken(c.key())
+			if saved.AccessToken != tt.want {
+				t.Errorf("saved token = %q, want %q", saved.AccessToken, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthRefreshTokenGrant(t *testing.T) {
+	e := newTokenEndpoint(t)
+	e.reply = func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token": "from-%s"}`, r.PostForm.Get("refresh_token"))
+	}
+	c := oauthConfig{grant: "refresh_token", tokenURL: e.URL, refreshToken: "given"}
+	token, err := oauthAccessToken(context.Background(), newTestStore(t), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "from-given" || token.RefreshToken != "given" || !token.ExpiresAt.IsZero() {
+		t.Errorf("token = %+v", token)
+	}
+	c.refreshToken = ""
+	if _, err := oauthAccessToken(context.Background(), newTestStore(t), c); err == nil || !strings.Contains(err.Error(), "missing refresh token") {
+		t.Errorf("err = %v, want missing refresh token", err)
+	}
+}
+
+func TestOAuthErrors(t *testing.T) {
+	e := newTokenEndpoint(t)
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusUnauthorized, `{"error": "invalid_client", "error_description": "bad secret"}`, "401 Unauthorized: invalid_client bad secret"},
+		{http.StatusBadGateway, `<html>gateway down</html>`, "502 Bad Gateway: <html>gateway down</html>"},
+		{http.StatusOK, `{"token_type": "bearer"}`, "200 OK without an access token"},
+	}
+	for _, tt := range tests {
+		e.reply = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}
+		c := oauthConfig{grant: "client_credentials", tokenURL: e.URL}
+		_, err := oauthAccessToken(context.Background(), newTestStore(t), c)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("err = %v, want %q", err, tt.want)
+		}
+	}
+	for _, c := range []oauthConfig{{grant: "client_credentials"}, {grant: "implicit", tokenURL: e.URL}} {
+		if _, err := oauthAccessToken(context.Background(), newTestStore(t), c); err == nil {
+			t.Errorf("oauthAccessToken(%+v) succeeded", c)
+		}
+	}
+}
+
+// the token endpoint is fetched outside of the trace of the request
+func TestOAuthNotTraced(t *testing.T) {
+	e := newTokenEndpoint(t)
+	e.reply = func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token": "abc"}`)
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer api.Close()
+
+	m := newTestModel(t)
+	m.url.SetValue(api.URL)
+	m.auth.cursor = oauth2Auth
+	m.auth.options[oauth2Auth].fields[oauthTokenURL].SetValue(e.URL)
+	msg, ok := sendRequest(m)().(response)
+	if !ok {
+		t.Fatal("sendRequest failed")
+	}
+	if msg.status != "200 OK" {
+		t.Errorf("status = %s, want 200 OK", msg.status)
+	}
+	if host := strings.TrimPrefix(e.URL, "http://"); strings.Contains(msg.traceLogs, host) {
+		t.Errorf("the trace has the token endpoint %s:\n%s", host, msg.traceLogs)
+	}
+}
+
+// the tokens of a config aren't given for another secret or password
+func TestOAuthKeyCredentials(t *testing.T) {
+	c := oauthConfig{grant: "password", tokenURL: "https://auth.example.com/token", clientID: "app", clientSecret: "s1", username: "ana", password: "p1"}
+	other := c
+	other.clientSecret = "s2"
+	if c.key() == other.key() {
+		t.Error("another client secret has the same key")
+	}
+	other = c
+	other.password = "p2"
+	if c.key() == other.key() {
+		t.Error("another password has the same key")
+	}
+	if strings.Contains(c.key(), "s1") || strings.Contains(c.key(), "p1") {
+		t.Errorf("the key has the credentials in clear: %q", c.key())
+	}
+}
+
+// a redirect URL without a port is served on port 80, the problem is told
+// when it can't be
+func TestOAuthRedirectPort(t *testing.T) {
+	authorize := func(redirect string) error {
+		_, err := authorizeWithPKCE(context.Background(), oauthConfig{
+			grant: "authorization_code", authURL: "https://auth.example.com/authorize", redirectURL: redirect,
+		})
+		return err
+	}
+	if err := authorize("https://127.0.0.1:8443/callback"); err == nil || !strings.Contains(err.Error(), "must be http") {
+		t.Errorf("https redirect: error %v", err)
+	}
+
+	taken, err := net.Listen("tcp", "127.0.0.1:80")
+	if err != nil {
+		t.Skipf("port 80 can't be taken: %v", err)
+	}
+	defer taken.Close()
+	if err := authorize("http://127.0.0.1/callback"); err == nil || !strings.Contains(err.Error(), "127.0.0.1:80") {
+		t.Errorf("redirect without a port: error %v, want the listen error on 127.0.0.1:80", err)
+	}
+}
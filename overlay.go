@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// listOverlay is what the palette, the search and the history browser have
+// in common: an input filtering a list that is walked with a cursor, over
+// the view it was opened from.
+type listOverlay struct {
+	input    textinput.Model
+	cursor   int
+	lastView int
+}
+
+func makeListOverlay(placeholder string) listOverlay {
+	input := makeInputField("", placeholder)
+	input.Prompt = "> "
+	return listOverlay{input: input}
+}
+
+func (o *listOverlay) open(v int) tea.Cmd {
+	o.lastView = view
+	o.input.Reset()
+	o.cursor = 0
+	view = v
+	mode = insert
+	return o.input.Focus()
+}
+
+func (o *listOverlay) close(nextView int) {
+	o.input.Blur()
+	view = nextView
+	mode = normal
+}
+
+// move handles the keys moving the cursor over the n items, it returns
+// false for the other keys.
+func (o *listOverlay) move(msg tea.KeyMsg, n int) bool {
+	switch {
+	case key.Matches(msg, keys.ListUp):
+		o.cursor = max(o.cursor-1, 0)
+	case key.Matches(msg, keys.ListDown):
+		o.cursor = min(o.cursor+1, max(n-1, 0))
+	default:
+		return false
+	}
+	return true
+}
+
+// clamp keeps the cursor on one of the n items after filtering
+func (o *listOverlay) clamp(n int) {
+	o.cursor = min(o.cursor, max(n-1, 0))
+}
+
+// window is the range of the n items shown, at most size of them with the
+// cursor in it.
+func (o listOverlay) window(n, size int) (int, int) {
+	start := max(0, o.cursor-size+1)
+	return start, min(n, start+size)
+}
+
+func (o listOverlay) mark(i int) string {
+	if i == o.cursor {
+		return "> "
+	}
+	return "  "
+}
+
+// renderOverlayHelp is the line of the bindings of an overlay
+func renderOverlayHelp(v int) string {
+	var help []string
+	for _, b := range helpFor(v).ShortHelp() {
+		help = append(help, b.Help().Key+" "+b.Help().Desc)
+	}
+	return lg.NewStyle().Foreground(placeHolderColor).Render(strings.Join(help, "  "))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// remapKeys loads the bindings of keys.json like main does
+func remapKeys(t *testing.T, config string) {
+	t.Helper()
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "tuisomnium")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "keys.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadKeyMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys = loaded
+	t.Cleanup(func() { keys = defaultKeyMap() })
+}
+
+func press(t *testing.T, m model, msg tea.KeyMsg) model {
+	t.Helper()
+	updated, _ := m.Update(msg)
+	return updated.(model)
+}
+
+// the overlays follow keys.json, the default keys are then typed
+func TestOverlayKeysRemapped(t *testing.T) {
+	m := newTestModel(t)
+	addRequest(&m, newRequest(m))
+	remapKeys(t, `{"listDown": ["ctrl+n"], "listUp": ["ctrl+p"], "select": ["ctrl+o"], "palette": ["ctrl+y"], "statusFilter": ["ctrl+t"]}`)
+	view, mode = requestsView, normal
+
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlY})
+	if view != paletteView {
+		t.Fatalf("view = %d, want the palette", view)
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	if m.palette.cursor != 0 {
+		t.Errorf("down moved the cursor once remapped: %d", m.palette.cursor)
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlN})
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlN})
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlP})
+	if m.palette.cursor != 1 {
+		t.Errorf("palette cursor = %d, want 1", m.palette.cursor)
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view != paletteView {
+		t.Errorf("enter selected once select was remapped")
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlO})
+	if view != requestsView || mode != normal || m.requests.cursor != 1 {
+		t.Errorf("select: view = %d, mode = %d, request = %d, want the second request", view, mode, m.requests.cursor)
+	}
+
+	m, _ = openHistoryBrowser(m)
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyTab})
+	if m.historyBrowser.status != 0 {
+		t.Errorf("tab cycled the status once remapped")
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlT})
+	if m.historyBrowser.status != 1 {
+		t.Errorf("status = %d, want 1", m.historyBrowser.status)
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if view != requestsView {
+		t.Errorf("esc left the history browser open")
+	}
+
+	m, _ = openSearch(m)
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlN})
+	if m.search.cursor != 0 {
+		t.Errorf("the cursor moved past the hits: %d", m.search.cursor)
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if view != requestsView {
+		t.Errorf("esc left the search open")
+	}
+}
+
+func TestListOverlayWindow(t *testing.T) {
+	tests := []struct{ cursor, n, size, start, end int }{
+		{0, 3, 10, 0, 3},
+		{0, 20, 10, 0, 10},
+		{9, 20, 10, 0, 10},
+		{12, 20, 10, 3, 13},
+		{19, 20, 10, 10, 20},
+	}
+	for _, tt := range tests {
+		o := listOverlay{cursor: tt.cursor}
+		if start, end := o.window(tt.n, tt.size); start != tt.start || end != tt.end {
+			t.Errorf("window(%d, %d) at %d = %d, %d, want %d, %d", tt.n, tt.size, tt.cursor, start, end, tt.start, tt.end)
+		}
+	}
+}
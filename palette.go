@@ -0,0 +1,164 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+const paletteMaxResults = 10
+
+// paletteItem is either a saved request to jump to or an action to run,
+// actions are run by replaying their binding in the requests view.
+type paletteItem struct {
+	title   string
+	request *DBRequest
+	binding *key.Binding
+}
+
+type paletteMatch struct {
+	item  paletteItem
+	score int
+}
+
+type palette struct {
+	listOverlay
+	matches []paletteMatch
+}
+
+var paletteActions = []paletteItem{
+	{title: "New request", binding: &keys.New},
+	{title: "Duplicate request", binding: &keys.Copy},
+	{title: "Delete request", binding: &keys.Delete},
+	{title: "Undo delete", binding: &keys.Undo},
+	{title: "Cycle sort mode", binding: &keys.Sort},
+	{title: "Cookie manager", binding: &keys.Cookies},
+	{title: "History browser", binding: &keys.History},
+	{title: "Search requests and responses", binding: &keys.Search},
+	{title: "Prune history", binding: &keys.Prune},
+}
+
+func makePalette() palette {
+	return palette{listOverlay: makeListOverlay("Search requests and actions...")}
+}
+
+// fuzzyScore matches pattern as a case insensitive subsequence of s, the
+// score rewards consecutive characters and matches at word starts.
+func fuzzyScore(pattern, s string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	p := []rune(strings.ToLower(pattern))
+	runes := []rune(s)
+	score, pi, streak := 0, 0, 0
+	for i, r := range runes {
+		if pi == len(p) {
+			break
+		}
+		if unicode.ToLower(r) != p[pi] {
+			streak = 0
+			continue
+		}
+		points := 1
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) {
+			points += 3
+		}
+		if streak > 0 {
+			points += 2 * streak
+		}
+		score += points
+		streak++
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	// prefer shorter candidates on equal matches
+	return score*100 - len(runes), true
+}
+
+func (p *palette) filter(requests []*DBRequest) {
+	pattern := p.input.Value()
+	p.matches = nil
+	for _, r := range requests {
+		best, found := 0, false
+		for _, s := range []string{r.Name, r.Method + " " + r.Url, r.Url} {
+			if score, ok := fuzzyScore(pattern, s); ok && (!found || score > best) {
+				best, found = score, true
+			}
+		}
+		if found {
+			p.matches = append(p.matches, paletteMatch{paletteItem{title: r.Name, request: r}, best})
+		}
+	}
+	for _, action := range paletteActions {
+		if score, ok := fuzzyScore(pattern, action.title); ok {
+			p.matches = append(p.matches, paletteMatch{action, score})
+		}
+	}
+	// with no pattern keep requests in sidebar order followed by actions
+	if pattern != "" {
+		slices.SortStableFunc(p.matches, func(a, b paletteMatch) int {
+			return b.score - a.score
+		})
+	}
+	p.clamp(len(p.matches))
+}
+
+func openPalette(m model) (model, tea.Cmd) {
+	cmd := m.palette.open(paletteView)
+	m.palette.filter(m.requests.items)
+	return m, cmd
+}
+
+func updatePalette(m model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, keys.Palette, keys.Back) {
+		m.palette.close(m.palette.lastView)
+		return m, nil
+	}
+	if m.palette.move(msg, len(m.palette.matches)) {
+		return m, nil
+	}
+	if key.Matches(msg, keys.Select) {
+		if len(m.palette.matches) == 0 {
+			return m, nil
+		}
+		item := m.palette.matches[m.palette.cursor].item
+		m.palette.close(requestsView)
+		if item.request != nil {
+			m.requests.cursor = slices.Index(m.requests.items, item.request)
+			showRequest(&m, item.request)
+			return m, nil
+		}
+		return m.Update(replay(*item.binding))
+	}
+	var cmd tea.Cmd
+	m.palette.input, cmd = m.palette.input.Update(msg)
+	m.palette.filter(m.requests.items)
+	return m, cmd
+}
+
+func renderPalette(m model) string {
+	lines := []string{m.palette.input.View(), ""}
+	start, end := m.palette.window(len(m.palette.matches), paletteMaxResults)
+	for i := start; i < end; i++ {
+		item := m.palette.matches[i].item
+		line := m.palette.mark(i) + item.title
+		if item.request == nil {
+			line += lg.NewStyle().Foreground(placeHolderColor).Render("  action")
+		}
+		lines = append(lines, line)
+	}
+	if len(m.palette.matches) == 0 {
+		lines = append(lines, lg.NewStyle().Foreground(placeHolderColor).Render("  no matches"))
+	} else if item := m.palette.matches[m.palette.cursor].item; item.request != nil {
+		// preview of the selected request
+		lines = append(lines, "", coloredMethod(item.request.Method)+" "+item.request.Url)
+	}
+	lines = append(lines, "", renderOverlayHelp(paletteView))
+	return secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+// the matches at the start, on word starts and in a row rank first
+func TestFuzzyScoreRanking(t *testing.T) {
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		// prefix over scattered
+		{"ord", "orders", "color grid"},
+		// word start over the middle of a word
+		{"user", "get-user", "reuser"},
+		// consecutive over spread across words
+		{"list", "list orders", "last item stock"},
+		// the shorter one on equal matches
+		{"ord", "orders", "orders archive"},
+	}
+	for _, tt := range tests {
+		better, ok := fuzzyScore(tt.pattern, tt.better)
+		if !ok {
+			t.Fatalf("%q doesn't match %q", tt.pattern, tt.better)
+		}
+		worse, ok := fuzzyScore(tt.pattern, tt.worse)
+		if !ok {
+			t.Fatalf("%q doesn't match %q", tt.pattern, tt.worse)
+		}
+		if better <= worse {
+			t.Errorf("%q: %q scores %d, not above %q with %d", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
+
+func TestFuzzyScoreMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"", "anything", true},
+		{"GET", "get users", true},
+		{"get", "GET /users", true},
+		{"ÉTÉ", "été", true},
+		{"xyz", "orders", false},
+		// a subsequence, in order
+		{"dro", "orders", false},
+		{"orders", "order", false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.pattern, tt.s); ok != tt.match {
+			t.Errorf("fuzzyScore(%q, %q) matches %v, want %v", tt.pattern, tt.s, ok, tt.match)
+		}
+	}
+	// case doesn't change the score
+	lower, _ := fuzzyScore("get", "get /users")
+	upper, _ := fuzzyScore("GET", "GET /users")
+	if lower != upper {
+		t.Errorf("the score depends on the case: %d and %d", lower, upper)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+)
+
+// retention limits the history kept, a zero limit is no limit. Pinned
+// responses are never pruned.
+type retention struct {
+	// the most recent responses kept for each request
+	KeepLast int
+	// responses older than that are pruned
+	MaxAgeDays int
+	// the oldest responses are pruned until the database fits
+	MaxSizeMB int
+}
+
+// set reports if a limit was configured, nothing is pruned otherwise
+func (r retention) set() bool {
+	return r != retention{}
+}
+
+// loadRetention reads retention.json of the config directory, e.g.
+// {"keepLast": 20, "maxAgeDays": 90, "maxSizeMB": 200}. A missing file
+// keeps the whole history.
+func loadRetention() (retention, error) {
+	var r retention
+	path, err := configPath("retention.json")
+	if err != nil {
+		return r, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if r.KeepLast < 0 || r.MaxAgeDays < 0 || r.MaxSizeMB < 0 {
+		return r, fmt.Errorf("%s: the limits can't be negative", path)
+	}
+	return r, nil
+}
+
+// pruneHistory prunes the store and drops the pruned responses of the
+// requests loaded, the trashed ones too, then moves the tabs showing a
+// pruned response to one that is left.
+func pruneHistory(m *model) {
+	if !m.retention.set() {
+		m.err = errors.New("no retention set in retention.json, the history is kept")
+		return
+	}
+	pruned, err := m.db.PruneResponses(m.retention)
+	if err != nil {
+		m.err = fmt.Errorf("failed to prune history: %w", err)
+		return
+	}
+	if len(pruned) == 0 {
+		return
+	}
+	// the response each tab shows, to find it again once the indexes moved
+	states := make([]*tabState, len(m.tabs))
+	shown := make([]int64, len(m.tabs))
+	for i, tab := range m.tabs {
+		states[i] = &m.tabs[i].state
+		if i == m.activeTab {
+			states[i] = &m.tabState
+		}
+		if cursor := states[i].history.cursor; cursor < len(tab.request.Responses) {
+			shown[i] = tab.request.Responses[cursor].ID
+		}
+	}
+	isPruned := func(res DBResponse) bool { return slices.Contains(pruned, res.ID) }
+	for _, r := range m.requests.items {
+		r.Responses = slices.DeleteFunc(r.Responses, isPruned)
+	}
+	for _, t := range m.trash {
+		t.request.Responses = slices.DeleteFunc(t.request.Responses, isPruned)
+	}
+	for i, tab := range m.tabs {
+		responses := tab.request.Responses
+		if index := slices.IndexFunc(responses, func(res DBResponse) bool { return res.ID == shown[i] }); index >= 0 {
+			states[i].history.cursor = index
+			continue
+		}
+		if len(responses) == 0 {
+			applyResponse(states[i], DBResponse{})
+			continue
+		}
+		cursor := min(states[i].history.cursor, len(responses)-1)
+		applyResponse(states[i], responses[cursor])
+		states[i].history.cursor = cursor
+	}
+	if len(m.requests.items) > 0 && len(m.requests.items[m.requests.cursor].Responses) == 0 && view == historyOptionsView {
+		view = historyView
+	}
+}
+
+// showHistoryEntry shows the response under the history cursor, or nothing
+// when the request has none left.
+func showHistoryEntry(m *model) {
+	if len(m.requests.items) == 0 {
+		return
+	}
+	responses := m.requests.items[m.requests.cursor].Responses
+	if len(responses) == 0 {
+		applyResponse(&m.tabState, DBResponse{})
+		if view == historyOptionsView {
+			view = historyView
+		}
+		return
+	}
+	cursor := min(m.history.cursor, len(responses)-1)
+	applyResponse(&m.tabState, responses[cursor])
+	m.history.cursor = cursor
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// addResponses stores n responses of the request, the most recent first
+// like the store loads them, each with its number in the trace logs.
+func addResponses(t *testing.T, s *Store, r *DBRequest, n int) {
+	t.Helper()
+	now := time.Now()
+	for i := range n {
+		res := DBResponse{RequestID: r.ID, Status: "200 OK", TraceLogs: fmt.Sprintf("%s %d", r.Name, i), ResponseAt: now.Add(-time.Duration(i) * time.Minute)}
+		if err := s.SaveResponse(&res); err != nil {
+			t.Fatal(err)
+		}
+		r.Responses = append(r.Responses, res)
+	}
+}
+
+// the responses pruned leave the trash and the tabs showing them
+func TestPruneHistory(t *testing.T) {
+	m := newTestModel(t)
+	a := m.requests.items[m.requests.cursor]
+	a.Name = "a"
+	addResponses(t, m.db, a, 3)
+	applyResponse(&m.tabState, a.Responses[2])
+	m.history.cursor = 2
+
+	b := newRequest(m)
+	b.Name = "b"
+	addRequest(&m, b)
+	addResponses(t, m.db, b, 3)
+	if err := m.db.PinResponse(b.Responses[2].ID, true); err != nil {
+		t.Fatal(err)
+	}
+	b.Responses[2].Pinned = true
+	applyResponse(&m.tabState, b.Responses[2])
+	m.history.cursor = 2
+
+	c := &DBRequest{Name: "c", Method: "GET"}
+	if err := m.db.SaveRequest(c); err != nil {
+		t.Fatal(err)
+	}
+	addResponses(t, m.db, c, 2)
+	m.trash = append(m.trash, trashedRequest{c, 0})
+
+	m.retention = retention{KeepLast: 1}
+	view = historyOptionsView
+	pruneHistory(&m)
+
+	for _, r := range []*DBRequest{a, b, c} {
+		want := 1
+		if r == b {
+			want = 2
+		}
+		if len(r.Responses) != want {
+			t.Errorf("%s has %d responses, want %d", r.Name, len(r.Responses), want)
+		}
+	}
+	// the pinned response is still shown, it moved up
+	if m.tabs[m.activeTab].request != b || m.history.cursor != 1 || m.resLogs != "b 2" {
+		t.Errorf("active tab: cursor %d shows %q, want 1 and the pinned response", m.history.cursor, m.resLogs)
+	}
+	// the tab in the background showed a pruned one
+	other := m.tabs[1-m.activeTab].state
+	if other.history.cursor != 0 || other.resLogs != "a 0" {
+		t.Errorf("tab of a: cursor %d shows %q, want the response left", other.history.cursor, other.resLogs)
+	}
+	if view != historyOptionsView {
+		t.Errorf("the history of the active request was closed")
+	}
+	switchTab(&m, 1-m.activeTab)
+	if m.history.cursor != 0 || m.resLogs != "a 0" {
+		t.Errorf("switching to the tab of a shows %q", m.resLogs)
+	}
+}
+
+// nothing is pruned until a retention is configured
+func TestRetentionOptIn(t *testing.T) {
+	m := newTestModel(t)
+	r, err := loadRetention()
+	if err != nil || r.set() {
+		t.Fatalf("loadRetention without a file = %+v, %v, want no limit", r, err)
+	}
+	a := m.requests.items[m.requests.cursor]
+	addResponses(t, m.db, a, 150)
+	m.retention = r
+	pruneHistory(&m)
+	if len(a.Responses) != 150 || m.err == nil {
+		t.Errorf("%d responses left, err %v, want all of them and the hint", len(a.Responses), m.err)
+	}
+	if pruned, err := m.db.PruneResponses(r); err != nil || len(pruned) != 0 {
+		t.Errorf("PruneResponses without limits pruned %v, %v", pruned, err)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+const (
+	searchMaxResults = 8
+	searchLimit      = 50
+	// the matches in the snippets are between these
+	snippetOpen, snippetClose = "\x02", "\x03"
+	// the text kept around the first match by likeSnippet
+	snippetContext = 40
+)
+
+// searchHit is a search result found in the requests loaded, index is the
+// response or -1 for the request itself.
+type searchHit struct {
+	request *DBRequest
+	index   int
+	snippet string
+}
+
+type search struct {
+	listOverlay
+	hits []searchHit
+}
+
+func makeSearch() search {
+	return search{listOverlay: makeListOverlay("Search requests and responses...")}
+}
+
+// likeSnippet marks the words in the text around the first one found, like
+// the snippet() of FTS5 does.
+func likeSnippet(text string, words []string) string {
+	var quoted []string
+	for _, word := range words {
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	loc := re.FindStringIndex(text)
+	if loc == nil {
+		return ""
+	}
+	start, end := max(loc[0]-snippetContext, 0), min(loc[1]+2*snippetContext, len(text))
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	snippet := re.ReplaceAllString(text[start:end], snippetOpen+"$0"+snippetClose)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func (s *search) run(m model) {
+	results, err := m.db.Search(s.input.Value(), searchLimit)
+	if err != nil {
+		log.Fatal("Error searching: ", err)
+	}
+	s.hits = nil
+	for _, result := range results {
+		i := slices.IndexFunc(m.requests.items, func(r *DBRequest) bool { return r.ID == result.RequestID })
+		if i < 0 {
+			continue
+		}
+		r := m.requests.items[i]
+		index := -1
+		if result.ResponseID != 0 {
+			if index = slices.IndexFunc(r.Responses, func(res DBResponse) bool { return res.ID == result.ResponseID }); index < 0 {
+				continue
+			}
+		}
+		s.hits = append(s.hits, searchHit{r, index, result.Snippet})
+	}
+	s.clamp(len(s.hits))
+}
+
+func openSearch(m model) (model, tea.Cmd) {
+	m.search.hits = nil
+	return m, m.search.open(searchView)
+}
+
+func updateSearch(m model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, keys.Back) {
+		m.search.close(m.search.lastView)
+		return m, nil
+	}
+	if m.search.move(msg, len(m.search.hits)) {
+		return m, nil
+	}
+	if key.Matches(msg, keys.Select) {
+		if len(m.search.hits) == 0 {
+			return m, nil
+		}
+		hit := m.search.hits[m.search.cursor]
+		m.search.close(requestsView)
+		if hit.index < 0 {
+			m.requests.cursor = slices.Index(m.requests.items, hit.request)
+			showRequest(&m, hit.request)
+			return m, nil
+		}
+		jumpToEntry(&m, historyEntry{request: hit.request, index: hit.index})
+		return m, nil
+	}
+	var cmd tea.Cmd
+	value := m.search.input.Value()
+	m.search.input, cmd = m.search.input.Update(msg)
+	if m.search.input.Value() != value {
+		m.search.run(m)
+	}
+	return m, cmd
+}
+
+// renderSnippet highlights the matches, the snippet is shown on one line
+func renderSnippet(snippet string) string {
+	snippet = strings.NewReplacer("\r", "", "\n", " ", "\t", " ").Replace(snippet)
+	faint := lg.NewStyle().Foreground(placeHolderColor)
+	match := lg.NewStyle().Foreground(orange).Bold(true)
+	var b strings.Builder
+	for i, part := range strings.Split(snippet, snippetOpen) {
+		if i == 0 {
+			b.WriteString(faint.Render(part))
+			continue
+		}
+		matched, rest, _ := strings.Cut(part, snippetClose)
+		b.WriteString(match.Render(matched) + faint.Render(rest))
+	}
+	return b.String()
+}
+
+func renderSearch(m model) string {
+	lines := []string{m.search.input.View()}
+	if !m.db.fts {
+		lines = append(lines, lg.NewStyle().Foreground(orange).Render("built without FTS5 (-tags sqlite_fts5), searching with LIKE"))
+	}
+	lines = append(lines, "")
+	start, end := m.search.window(len(m.search.hits), searchMaxResults)
+	for i := start; i < end; i++ {
+		hit := m.search.hits[i]
+		title := coloredMethod(hit.request.Method) + " " + hit.request.Name
+		if hit.index >= 0 {
+			res := hit.request.Responses[hit.index]
+			title = getStatusStyle(res.Status).Render(res.Status) + " " + coloredMethod(res.RequestMethod) + " " + res.RequestUrl +
+				lg.NewStyle().Foreground(placeHolderColor).Render("  "+res.ResponseAt.Format("Jan 02 15:04")+" in "+hit.request.Name)
+		}
+		lines = append(lines,
+			lg.NewStyle().MaxWidth(rightPanelWidth).Render(m.search.mark(i)+title),
+			lg.NewStyle().MaxWidth(rightPanelWidth).Render("    "+renderSnippet(hit.snippet)))
+	}
+	if len(m.search.hits) == 0 && strings.TrimSpace(m.search.input.Value()) != "" {
+		lines = append(lines, lg.NewStyle().Foreground(placeHolderColor).Render("  no matches"))
+	}
+	lines = append(lines, "", renderOverlayHelp(searchView))
+	return secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(strings.Join(lines, "\n"))
+}
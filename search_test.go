@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func seedSearch(t *testing.T, s *Store) (orders, users *DBRequest, declined, teapot int64) {
+	t.Helper()
+	orders = &DBRequest{Name: "Create order", Method: "POST", Url: "https://api.example.com/orders",
+		Headers: []NameValue{{"X-Tenant", "acme_100%"}}}
+	users = &DBRequest{Name: "List users", Method: "GET", Url: "http://other.org/users"}
+	for _, r := range []*DBRequest{orders, users} {
+		if err := s.SaveRequest(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	responses := []DBResponse{
+		{RequestID: orders.ID, Status: "500 Internal Server Error", Body: `{"error": "payment declined", "code": "E4217"}`, ResponseAt: now},
+		{RequestID: users.ID, Status: "418 I'm a teapot", Body: "Ünïcode body with the word brewed", ResponseAt: now.Add(time.Minute)},
+	}
+	for i := range responses {
+		if err := s.SaveResponse(&responses[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return orders, users, responses[0].ID, responses[1].ID
+}
+
+// testSearch runs the same queries on the FTS5 and the LIKE search
+func testSearch(t *testing.T, s *Store) {
+	orders, users, declined, teapot := seedSearch(t, s)
+	mark := func(word string) string { return snippetOpen + word + snippetClose }
+	tests := []struct {
+		query   string
+		want    []SearchResult
+		snippet string
+	}{
+		{"E4217", []SearchResult{{RequestID: orders.ID, ResponseID: declined}}, mark("E4217")},
+		{"order", []SearchResult{{RequestID: orders.ID}}, mark("order")},
+		{"acme", []SearchResult{{RequestID: orders.ID}}, "X-Tenant: " + mark("acme")},
+		{"declined payment", []SearchResult{{RequestID: orders.ID, ResponseID: declined}}, mark("declined")},
+		{"teapot", []SearchResult{{RequestID: users.ID, ResponseID: teapot}}, mark("teapot")},
+		{"declined teapot", nil, ""},
+		{`"quote`, nil, ""},
+		{"   ", nil, ""},
+	}
+	for _, tt := range tests {
+		results, err := s.Search(tt.query, searchLimit)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if len(results) != len(tt.want) {
+			t.Fatalf("Search(%q) = %q, want %d results", tt.query, results, len(tt.want))
+		}
+		for i, r := range results {
+			if r.RequestID != tt.want[i].RequestID || r.ResponseID != tt.want[i].ResponseID {
+				t.Errorf("Search(%q)[%d] = %d/%d, want %d/%d", tt.query, i, r.RequestID, r.ResponseID, tt.want[i].RequestID, tt.want[i].ResponseID)
+			}
+			if !strings.Contains(r.Snippet, tt.snippet) {
+				t.Errorf("Search(%q)[%d] snippet %q doesn't contain %q", tt.query, i, r.Snippet, tt.snippet)
+			}
+		}
+	}
+
+	// the index follows the edits and the deletes
+	orders.Name = "Refund payment"
+	if err := s.SaveRequest(orders); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := s.Search("refund", searchLimit); len(results) != 1 {
+		t.Errorf("Search(refund) after the rename = %q", results)
+	}
+	if err := s.DeleteResponse(declined); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := s.Search("E4217", searchLimit); len(results) != 0 {
+		t.Errorf("Search(E4217) after the delete = %q", results)
+	}
+	if err := s.DeleteRequest(users); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := s.Search("teapot", searchLimit); len(results) != 0 {
+		t.Errorf("Search(teapot) of a trashed request = %q", results)
+	}
+}
+
+func TestSearchFTS(t *testing.T) {
+	s := newTestStore(t)
+	if !s.fts {
+		t.Skip("built without FTS5, run with -tags sqlite_fts5")
+	}
+	testSearch(t, s)
+}
+
+func TestSearchLike(t *testing.T) {
+	s := newTestStore(t)
+	s.fts = false
+	testSearch(t, s)
+}
+
+func TestLikeSnippet(t *testing.T) {
+	text := strings.Repeat("é", 60) + " Ünïcode " + strings.Repeat("ü", 200)
+	snippet := likeSnippet(text, []string{"ünï"})
+	if !utf8.ValidString(snippet) {
+		t.Fatalf("snippet %q isn't valid UTF-8", snippet)
+	}
+	if !strings.Contains(snippet, snippetOpen+"Ünï"+snippetClose+"code") {
+		t.Errorf("snippet %q doesn't mark the match", snippet)
+	}
+	if !strings.HasPrefix(snippet, "…é") || !strings.HasSuffix(snippet, "ü…") {
+		t.Errorf("snippet %q isn't cut on both sides", snippet)
+	}
+
+	if got := likeSnippet("a.b a*b", []string{"a*b"}); got != "a.b "+snippetOpen+"a*b"+snippetClose {
+		t.Errorf("likeSnippet quoted the pattern wrong: %q", got)
+	}
+	if got := likeSnippet("nothing here", []string{"missing"}); got != "" {
+		t.Errorf("likeSnippet without a match = %q", got)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// sensitiveFields are the secret fields of every auth type, they are
+// encrypted in the store and masked in the UI.
+var sensitiveFields = map[int][]int{
+	basicAuth:  {1},
+	digestAuth: {1},
+	tokenAuth:  {1},
+	customAuth: {0},
+	oauth2Auth: {oauthClientSecret, oauthPassword, oauthRefreshToken},
+	awsAuth:    {awsSecretKey, awsSessionToken},
+	apiKeyAuth: {1},
+	hmacAuth:   {1},
+	jwtAuth:    {1},
+}
+
+func sensitive(authType, field int) bool {
+	for _, i := range sensitiveFields[authType] {
+		if i == field {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	encryptedPrefix  = "enc:v1:"
+	secretsCheck     = "tuisomnium"
+	pbkdf2Iterations = 600_000
+	passphraseEnv    = "TUISOMNIUM_PASSPHRASE"
+	keyringService   = "tuisomnium"
+)
+
+// secretBox encrypts the secrets with AES-GCM, every value gets its own nonce
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+func newSecretBox(key []byte) (*secretBox, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &secretBox{aead}, nil
+}
+
+func (b *secretBox) seal(plain string) string {
+	if b == nil || plain == "" {
+		return plain
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	rand.Read(nonce)
+	sealed := b.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// open decrypts a sealed value, values saved before encryption are returned as is
+func (b *secretBox) open(value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+	if b == nil {
+		return "", errors.New("secrets are locked")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", errors.New("malformed secret")
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plain, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("unable to decrypt secret, wrong key")
+	}
+	return string(plain), nil
+}
+
+// deriveKey derives the AES-256 key of a passphrase with PBKDF2-HMAC-SHA256
+func deriveKey(passphrase string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, pbkdf2Iterations, 32, sha256.New)
+}
+
+// unlockSecrets sets up the key of the store secrets. The first run keeps a
+// random key in the OS keyring when there is one, otherwise (or when
+// TUISOMNIUM_PASSPHRASE is set) the key is derived from a passphrase that
+// is asked on every start.
+func unlockSecrets(s *Store) error {
+	source, err := s.GetSetting("secrets.source", "")
+	if err != nil {
+		return err
+	}
+	var key []byte
+	switch source {
+	case "":
+		return setupSecrets(s)
+	case "keyring":
+		encoded, err := keyringGet()
+		if err != nil {
+			return fmt.Errorf("unable to read the key from the OS keyring: %w", err)
+		}
+		if key, err = hex.DecodeString(encoded); err != nil {
+			return fmt.Errorf("malformed key in the OS keyring: %w", err)
+		}
+	case "passphrase":
+		encodedSalt, err := s.GetSetting("secrets.salt", "")
+		if err != nil {
+			return err
+		}
+		salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+		if err != nil {
+			return fmt.Errorf("malformed salt: %w", err)
+		}
+		// give a mistyped passphrase a couple more chances
+		for attempt := 0; ; attempt++ {
+			passphrase, err := readPassphrase("Passphrase: ")
+			if err != nil {
+				return err
+			}
+			key = deriveKey(passphrase, salt)
+			if err = checkKey(s, key); err == nil || attempt == 2 || os.Getenv(passphraseEnv) != "" {
+				break
+			}
+			fmt.Fprintln(os.Stderr, "Wrong passphrase, try again.")
+		}
+	default:
+		return fmt.Errorf("unknown secrets source %q", source)
+	}
+	if err := checkKey(s, key); err != nil {
+		return err
+	}
+	s.secrets, _ = newSecretBox(key)
+	return nil
+}
+
+func checkKey(s *Store, key []byte) error {
+	box, err := newSecretBox(key)
+	if err != nil {
+		return err
+	}
+	check, err := s.GetSetting("secrets.check", "")
+	if err != nil {
+		return err
+	}
+	if plain, err := box.open(check); err != nil || plain != secretsCheck {
+		return errors.New("wrong key for the secrets, unable to decrypt them")
+	}
+	return nil
+}
+
+// setupSecrets creates the key and encrypts the secrets saved so far
+func setupSecrets(s *Store) error {
+	key := make([]byte, 32)
+	rand.Read(key)
+	source := "keyring"
+	if os.Getenv(passphraseEnv) != "" || keyringSet(hex.EncodeToString(key)) != nil {
+		source = "passphrase"
+		fmt.Fprintln(os.Stderr, "Secrets (passwords, tokens) are encrypted with a passphrase asked on every start.")
+		passphrase, err := readPassphrase("New passphrase: ")
+		if err != nil {
+			return err
+		}
+		if os.Getenv(passphraseEnv) == "" {
+			confirm, err := readPassphrase("Confirm passphrase: ")
+			if err != nil {
+				return err
+			}
+			if confirm != passphrase {
+				return errors.New("the passphrases don't match")
+			}
+		}
+		salt := make([]byte, 16)
+		rand.Read(salt)
+		key = deriveKey(passphrase, salt)
+		if err := s.SetSetting("secrets.salt", base64.StdEncoding.EncodeToString(salt)); err != nil {
+			return err
+		}
+	}
+
+	box, err := newSecretBox(key)
+	if err != nil {
+		return err
+	}
+	requests, err := s.GetRequests()
+	if err != nil {
+		return err
+	}
+	tokens, err := s.GetTokens()
+	if err != nil {
+		return err
+	}
+	s.secrets = box
+	for _, r := range requests {
+		if err := s.SaveRequest(&r); err != nil {
+			return err
+		}
+	}
+	for key, token := range tokens {
+		if err := s.SaveToken(key, token); err != nil {
+			return err
+		}
+	}
+	if err := s.SetSetting("secrets.check", box.seal(secretsCheck)); err != nil {
+		return err
+	}
+	return s.SetSetting("secrets.source", source)
+}
+
+// stdin is shared by the prompts, a reader of its own for each one could
+// buffer the lines of the next prompts and lose them
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassphrase reads TUISOMNIUM_PASSPHRASE, or the terminal without echo
+func readPassphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		line, err := stdin.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	passphrase, err := term.ReadPassword(os.Stdin.Fd())
+	return string(passphrase), err
+}
+
+// keyringGet and keyringSet use the command line tool of the OS keyring,
+// secret-tool (libsecret) on Linux and security on macOS.
+func keyringGet() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", "secrets", "-w")
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", "secrets")
+	default:
+		return "", errors.New("no supported keyring")
+	}
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func keyringSet(secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// the command is read from stdin, the arguments of a process can be
+		// read by anyone with ps
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a secrets -w %q\n", keyringService, secret))
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "store", "--label=tuisomnium secrets", "service", keyringService, "account", "secrets")
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		return errors.New("no supported keyring")
+	}
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	// a keyring that doesn't give the key back is no use
+	stored, err := keyringGet()
+	if err != nil || stored != secret {
+		return errors.New("the keyring didn't keep the key")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// the key of a passphrase never changes, the stores set up before keep
+// unlocking
+func TestDeriveKey(t *testing.T) {
+	want := "91828083f760ed60bb550e24f3e89aa29bbcb27c4219d034c4c4f03c2e6db9d9"
+	if got := hex.EncodeToString(deriveKey("correct horse", []byte("0123456789abcdef"))); got != want {
+		t.Errorf("deriveKey = %s, want %s", got, want)
+	}
+}
+
+func TestSecretBox(t *testing.T) {
+	box, err := newSecretBox(deriveKey("passphrase", []byte("salt")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := box.seal("s3cret")
+	if sealed == "s3cret" || sealed == box.seal("s3cret") {
+		t.Fatalf("seal = %q, want a value encrypted with its own nonce", sealed)
+	}
+	if plain, err := box.open(sealed); err != nil || plain != "s3cret" {
+		t.Errorf("open = %q, %v", plain, err)
+	}
+	if plain, err := box.open("saved before encryption"); err != nil || plain != "saved before encryption" {
+		t.Errorf("open of a plain value = %q, %v", plain, err)
+	}
+	other, _ := newSecretBox(make([]byte, 32))
+	if _, err := other.open(sealed); err == nil {
+		t.Error("open with the wrong key succeeded")
+	}
+}
+
+// the new passphrase and its confirmation piped in are read one line each
+func TestReadPassphrasePiped(t *testing.T) {
+	if term.IsTerminal(os.Stdin.Fd()) {
+		t.Skip("stdin is a terminal")
+	}
+	t.Setenv(passphraseEnv, "")
+	defer func(r *bufio.Reader) { stdin = r }(stdin)
+	stdin = bufio.NewReader(strings.NewReader("first\nsecond\n"))
+	for _, want := range []string{"first", "second"} {
+		if got, err := readPassphrase(""); err != nil || got != want {
+			t.Errorf("readPassphrase = %q, %v, want %q", got, err, want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+// fields of the AWS SigV4 auth type
+const (
+	awsAccessKey int = iota
+	awsSecretKey
+	awsSessionToken
+	awsRegion
+	awsService
+)
+
+var awsLabels = []string{"Access key ID", "Secret key", "Session token", "Region", "Service"}
+
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+type awsCredentials struct {
+	accessKey, secretKey, sessionToken string
+	region, service                    string
+}
+
+func awsCredentialsFrom(fields []textinput.Model) awsCredentials {
+	value := func(i int) string { return strings.TrimSpace(fields[i].Value()) }
+	return awsCredentials{
+		accessKey:    value(awsAccessKey),
+		secretKey:    value(awsSecretKey),
+		sessionToken: value(awsSessionToken),
+		region:       cmp.Or(value(awsRegion), "us-east-1"),
+		service:      cmp.Or(value(awsService), "execute-api"),
+	}
+}
+
+// signSigV4 signs the request as it is about to be sent, it returns the
+// canonical request and the string to sign for the trace logs.
+func signSigV4(req *http.Request, body string, c awsCredentials, now time.Time) (canonical, stringToSign string) {
+	now = now.UTC()
+	amzDate, date := now.Format("20060102T150405Z"), now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+	// only S3 wants the payload hash as a header
+	if c.service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers := map[string]string{"host": cmp.Or(req.Host, req.URL.Host)}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			var trimmed []string
+			for _, value := range values {
+				trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+			}
+			headers[name] = strings.Join(trimmed, ",")
+		}
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical = strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL, c.service),
+		canonicalQuery(req.URL),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, c.region, c.service, "aws4_request"}, "/")
+	stringToSign = strings.Join([]string{sigV4Algorithm, amzDate, scope, sha256Hex(canonical)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	for _, part := range []string{c.region, c.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, c.accessKey, scope, signedHeaders, signature))
+	return canonical, stringToSign
+}
+
+// canonicalURI encodes every path segment, twice for all services but S3
+func canonicalURI(u *url.URL, service string) string {
+	if u.Path == "" {
+		return "/"
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		segments[i] = awsURIEncode(segment)
+		if service != "s3" {
+			segments[i] = awsURIEncode(segments[i])
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery sorts the encoded parameters by name, then by value
+func canonicalQuery(u *url.URL) string {
+	var pairs [][2]string
+	for name, values := range u.Query() {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{awsURIEncode(name), awsURIEncode(value)})
+		}
+	}
+	slices.SortFunc(pairs, func(a, b [2]string) int {
+		return cmp.Or(strings.Compare(a[0], b[0]), strings.Compare(a[1], b[1]))
+	})
+	var encoded []string
+	for _, pair := range pairs {
+		encoded = append(encoded, pair[0]+"="+pair[1])
+	}
+	return strings.Join(encoded, "&")
+}
+
+// awsURIEncode escapes everything but the unreserved characters of RFC 3986
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the credentials and the date of the AWS SigV4 test suite
+var (
+	testAWSCredentials = awsCredentials{
+		accessKey: "AKIDEXAMPLE",
+		secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		region:    "us-east-1",
+		service:   "service",
+	}
+	testAWSDate = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+)
+
+const testSTSToken = "AQoDYXdzEPT//////////wEXAMPLEtc764bNrC9SAPBSM22wDOk4x4HIZ8j4FZTwdQWLWsKWHGBuFqwAeMicRXmxfpSPfIeoIYRqTflfKD8YUuwthAx7mSEI/qkPpKPi/kMcGdQrmGdeehM4IC1NtBmUpp2wUE8phUZampKsburEDy0KPkyQDYwT7WZ0wq5VSXDvp75YU9HFvlRd8Tx6q6fE8YQcHNVXAkiY9q6d+xo0rKwT38xVqr7ZD0u0iPPkUL64lIZbqBAz+scqKmlzm8FDrypNC9Yjc8fPOLn9FX9KSYvKTr4rvx3iSIlTJabIQwj2ICCR/oLxBA=="
+
+func TestSignSigV4Suite(t *testing.T) {
+	tests := []struct {
+		name                       string
+		method, url, body, content string
+		sessionToken               string
+		signedHeaders, signature   string
+	}{
+		{"get-vanilla", "GET", "/", "", "", "",
+			"host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "GET", "/?Param2=value2&Param1=value1", "", "", "",
+			"host;x-amz-date", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-vanilla-query-order-value", "GET", "/?Param1=value2&Param1=value1", "", "", "",
+			"host;x-amz-date", "5772eed61e12b33fae39ee5e7012498b51d56abc0abb7c60486157bd471c4694"},
+		{"get-vanilla-query-unreserved", "GET", "/?-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz=-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", "", "", "",
+			"host;x-amz-date", "9c3e54bfcdf0b19771a7f523ee5669cdf59bc7cc0884027167c21bb143a40197"},
+		{"get-vanilla-utf8-query", "GET", "/?ሴ=bar", "", "", "",
+			"host;x-amz-date", "2cdec8eed098649ff3a119c94853b13c643bcf08f8b0a1d91e12c9027818dd04"},
+		{"post-vanilla", "POST", "/", "", "", "",
+			"host;x-amz-date", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"post-vanilla-query", "POST", "/?Param1=value1", "", "", "",
+			"host;x-amz-date", "28038455d6de14eafc1f9222cf5aa6f1a96197d7deb8263271d420d138af7f11"},
+		{"post-x-www-form-urlencoded", "POST", "/", "Param1=value1", "application/x-www-form-urlencoded", "",
+			"content-type;host;x-amz-date", "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+		{"post-sts-header-before", "POST", "/", "", "", testSTSToken,
+			"host;x-amz-date;x-amz-security-token", "85d96828115b5dc0cfc3bd16ad9e210dd772bbebba041836c64533a82be05ead"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, "https://example.amazonaws.com"+tt.url, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.content != "" {
+			req.Header.Set("Content-Type", tt.content)
+		}
+		c := testAWSCredentials
+		c.sessionToken = tt.sessionToken
+		signSigV4(req, tt.body, c, testAWSDate)
+
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" +
+			tt.signedHeaders + ", Signature=" + tt.signature
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s: Authorization = %s, want %s", tt.name, got, want)
+		}
+		if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+			t.Errorf("%s: X-Amz-Date = %s", tt.name, got)
+		}
+		if got := req.Header.Get("X-Amz-Security-Token"); got != tt.sessionToken {
+			t.Errorf("%s: X-Amz-Security-Token = %q, want %q", tt.name, got, tt.sessionToken)
+		}
+	}
+}
+
+// the canonical request and the string to sign of get-vanilla, the payload
+// is empty
+func TestSignSigV4Canonical(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	canonical, stringToSign := signSigV4(req, "", testAWSCredentials, testAWSDate)
+	wantCanonical := "GET\n/\n\nhost:example.amazonaws.com\nx-amz-date:20150830T123600Z\n\nhost;x-amz-date\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if canonical != wantCanonical {
+		t.Errorf("canonical request = %q, want %q", canonical, wantCanonical)
+	}
+	wantStringToSign := "AWS4-HMAC-SHA256\n20150830T123600Z\n20150830/us-east-1/service/aws4_request\n" +
+		"bb579772317eb040ac9ed261061d46c1f17a8133879d6129b6e1c25292927e63"
+	if stringToSign != wantStringToSign {
+		t.Errorf("string to sign = %q, want %q", stringToSign, wantStringToSign)
+	}
+}
+
+// S3 sends the payload hash, the empty one here, and encodes the path once
+func TestSignSigV4S3(t *testing.T) {
+	req, _ := http.NewRequest("PUT", "https://bucket.s3.amazonaws.com/a%20b/c", nil)
+	c := testAWSCredentials
+	c.service = "s3"
+	canonical, _ := signSigV4(req, "", c, testAWSDate)
+	const empty = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got := req.Header.Get("X-Amz-Content-Sha256"); got != empty {
+		t.Errorf("X-Amz-Content-Sha256 = %q, want the hash of the empty payload", got)
+	}
+	lines := strings.Split(canonical, "\n")
+	if lines[1] != "/a%20b/c" || !strings.Contains(canonical, "x-amz-content-sha256:"+empty) || lines[len(lines)-1] != empty {
+		t.Errorf("canonical request = %q", canonical)
+	}
+
+	// the other services encode the path twice
+	req, _ = http.NewRequest("GET", "https://example.amazonaws.com/a%20b", nil)
+	if canonical, _ := signSigV4(req, "", testAWSCredentials, testAWSDate); strings.Split(canonical, "\n")[1] != "/a%2520b" {
+		t.Errorf("canonical URI = %q, want /a%%2520b", strings.Split(canonical, "\n")[1])
+	}
+}
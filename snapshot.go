@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+const redacted = "<redacted>"
+
+// redactor hides the credentials of a request in what is kept with the
+// response: the headers set by the auth and any secret value, like an API
+// key sent in the query.
+type redactor struct {
+	headers map[string]bool
+	secrets []string
+}
+
+func newRedactor(authHeaders []string, secrets []string) redactor {
+	r := redactor{headers: map[string]bool{"Authorization": true, "Proxy-Authorization": true}}
+	for _, name := range authHeaders {
+		r.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, secret := range secrets {
+		// short values would redact half the request
+		if len(secret) >= 4 {
+			r.secrets = append(r.secrets, secret, url.QueryEscape(secret))
+		}
+	}
+	return r
+}
+
+func (r redactor) text(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+func (r redactor) header(name, value string) string {
+	if r.headers[http.CanonicalHeaderKey(name)] {
+		return redacted
+	}
+	return r.text(value)
+}
+
+// cookieHeaders are redacted from the raw messages too, the cookie jar
+// keeps the values encrypted
+var cookieHeaders = map[string]bool{"Cookie": true, "Set-Cookie": true}
+
+// lines redacts the "Name: value" lines of raw messages
+func (r redactor) lines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		name, _, ok := strings.Cut(line, ":")
+		if ok && (r.headers[http.CanonicalHeaderKey(name)] || cookieHeaders[http.CanonicalHeaderKey(name)]) {
+			cr := ""
+			if strings.HasSuffix(line, "\r") {
+				cr = "\r"
+			}
+			lines[i] = name + ": " + redacted + cr
+		}
+	}
+	return r.text(strings.Join(lines, "\n"))
+}
+
+// authSecrets are the values of the sensitive fields of the auth sent
+func authSecrets(m model, token oauthToken) []string {
+	secrets := []string{token.AccessToken}
+	for _, i := range sensitiveFields[m.auth.cursor] {
+		secrets = append(secrets, m.auth.options[m.auth.cursor].fields[i].Value())
+	}
+	return secrets
+}
+
+// requestSnapshot keeps what the editor had when the request was sent and
+// the headers that went out, without the credentials.
+func requestSnapshot(m model, req *http.Request, r redactor) *RequestSnapshot {
+	s := &RequestSnapshot{
+		Method:   req.Method,
+		Url:      r.text(m.url.Value()),
+		SentUrl:  r.text(req.URL.String()),
+		Query:    nameValues(m.queryParams.fields),
+		Headers:  nameValues(m.headers.fields),
+		BodyType: m.body.options[m.body.cursor].type_,
+		Body:     m.body.field.Value(),
+		AuthType: m.auth.options[m.auth.cursor].name,
+	}
+	for i := range s.Query {
+		s.Query[i].Value = r.text(s.Query[i].Value)
+	}
+	for i := range s.Headers {
+		s.Headers[i].Value = r.header(s.Headers[i].Name, s.Headers[i].Value)
+	}
+	var names []string
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			s.Sent = append(s.Sent, NameValue{name, r.header(name, value)})
+		}
+	}
+	return s
+}
+
+// restoreSnapshot puts the request of a history entry back in the request,
+// the auth values were never kept so only the auth type is restored and the
+// redacted values are taken from the request.
+func restoreSnapshot(r *DBRequest, s *RequestSnapshot) {
+	r.Method, r.Url = s.Method, s.Url
+	r.Query = unredacted(s.Query, r.Query)
+	r.Headers = unredacted(s.Headers, r.Headers)
+	for i, type_ := range r.Body.Types {
+		if type_.Name == s.BodyType {
+			r.Body.Selected = i
+			type_.Value = s.Body
+		}
+	}
+	for i, type_ := range r.Auth.Types {
+		if type_.Type == s.AuthType {
+			r.Auth.Selected = i
+		}
+	}
+}
+
+// unredacted takes the redacted values from the current ones of the same name
+func unredacted(snapshot, current []NameValue) []NameValue {
+	values := slices.Clone(snapshot)
+	for i, value := range values {
+		if !strings.Contains(value.Value, redacted) {
+			continue
+		}
+		if j := slices.IndexFunc(current, func(c NameValue) bool { return strings.EqualFold(c.Name, value.Name) }); j >= 0 {
+			values[i].Value = current[j].Value
+		}
+	}
+	return values
+}
+
+func renderSnapshot(s *RequestSnapshot) string {
+	faint := lg.NewStyle().Foreground(placeHolderColor)
+	bold := lg.NewStyle().Bold(true)
+	if s == nil {
+		return faint.Render("only the method and the URL were kept for this response")
+	}
+	lines := []string{bold.Render(s.Method + " " + s.SentUrl)}
+	for _, header := range s.Sent {
+		lines = append(lines, header.Name+": "+header.Value)
+	}
+	lines = append(lines, faint.Render("auth: "+s.AuthType+", body: "+s.BodyType))
+	if s.Body != "" {
+		lines = append(lines, s.Body)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// legacySnapshot is the request of a response saved before the snapshots,
+// recovered from what the raw capture shows was sent. Without a capture
+// only the method and the URL are known, the rest of the tab is kept.
+func legacySnapshot(m model, res DBResponse) *RequestSnapshot {
+	s := &RequestSnapshot{Method: res.RequestMethod, Url: res.RequestUrl,
+		Query: nameValues(m.queryParams.fields), Headers: nameValues(m.headers.fields)}
+	_, sent, found := strings.Cut(res.Raw, "── Sent (")
+	if !found {
+		return s
+	}
+	_, sent, _ = strings.Cut(sent, "\n")
+	sent, _, _ = strings.Cut(sent, "\n── ")
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(sent)))
+	if err != nil {
+		return s
+	}
+
+	// the query params are what was sent on top of the URL typed
+	typed := url.Values{}
+	if u, err := url.Parse(res.RequestUrl); err == nil {
+		typed = u.Query()
+	}
+	s.Query = nil
+	query := req.URL.Query()
+	for _, name := range slices.Sorted(maps.Keys(query)) {
+		for _, value := range query[name] {
+			if !slices.Contains(typed[name], value) {
+				s.Query = append(s.Query, NameValue{name, value})
+			}
+		}
+	}
+	// what the transport adds, and the redacted headers that the auth or
+	// the cookie jar set, aren't headers of the request
+	s.Headers = nil
+	for _, name := range slices.Sorted(maps.Keys(req.Header)) {
+		for _, value := range req.Header[name] {
+			switch {
+			case value == redacted, name == "Content-Length",
+				name == "User-Agent" && strings.HasPrefix(value, "Go-http-client/"),
+				name == "Accept-Encoding" && value == "gzip":
+				continue
+			}
+			s.Headers = append(s.Headers, NameValue{name, value})
+		}
+	}
+	// a body summed up in the capture isn't restored
+	body, err := io.ReadAll(req.Body)
+	switch {
+	case err != nil || int64(len(body)) != req.ContentLength && req.ContentLength >= 0:
+	case len(body) == 0:
+		s.BodyType = "No body"
+	case json.Valid(body):
+		s.BodyType, s.Body = "Json", string(body)
+	case strings.HasPrefix(strings.TrimSpace(string(body)), "<"):
+		s.BodyType, s.Body = "Xml", string(body)
+	default:
+		s.BodyType, s.Body = "Plain", string(body)
+	}
+	return s
+}
+
+// restoreRequest puts the request of the history entry in the tab, the
+// auth fields keep their values as the snapshot doesn't have them.
+func restoreRequest(m *model, res DBResponse) {
+	r := m.tabs[m.activeTab].request.Copy(m.tabs[m.activeTab].request.Name)
+	// the redacted values come from the tab, it may have unsaved ones
+	r.Query, r.Headers = nameValues(m.queryParams.fields), nameValues(m.headers.fields)
+	restoreSnapshot(r, res.Request)
+	var values [][]string
+	for _, option := range m.auth.options {
+		var fields []string
+		for _, field := range option.fields {
+			fields = append(fields, field.Value())
+		}
+		values = append(values, fields)
+	}
+	cursor := m.history.cursor
+	setUIRequest(m, r)
+	for i, option := range m.auth.options {
+		for j := range option.fields {
+			option.fields[j].SetValue(values[i][j])
+		}
+	}
+	applyResponse(&m.tabState, res)
+	m.history.cursor = cursor
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -13,6 +15,38 @@ type NameValue struct {
 	Name  string
 	Value string
 }
+
+// RequestSnapshot is the request of a response as it was sent, the auth
+// credentials are redacted.
+type RequestSnapshot struct {
+	Method   string
+	Url      string // as typed, SentUrl has the query params
+	SentUrl  string
+	Query    []NameValue
+	Headers  []NameValue
+	BodyType string
+	Body     string
+	AuthType string
+	// the headers sent, the auth ones included
+	Sent []NameValue
+}
+
+// ResponseCookie is a Set-Cookie of a response, responses saved before the
+// attributes were kept only have the name and the value.
+type ResponseCookie struct {
+	Name        string
+	Value       string
+	Domain      string
+	Path        string
+	Expires     time.Time
+	MaxAge      int // as in http.Cookie, negative deletes the cookie
+	Secure      bool
+	HttpOnly    bool
+	SameSite    string
+	Partitioned bool
+	// why a browser would reject it
+	Warnings []string
+}
 type dbAuthType struct {
 	Type   string
 	Fields []*NameValue
@@ -36,6 +70,7 @@ type DBRequest struct {
 	Auth      dbAuth
 	Query     []NameValue
 	Headers   []NameValue
+	Position  int
 	Responses []DBResponse
 }
 type DBResponse struct {
@@ -46,20 +81,92 @@ type DBResponse struct {
 	Body          string
 	Status        string
 	Headers       []NameValue
-	Cookies       []NameValue
+	Cookies       []ResponseCookie
 	Duration      string
 	Size          string
 	ResponseAt    time.Time
 	TraceLogs     string
+	Timing        Timing
+	Raw           string
+	Request       *RequestSnapshot // nil for the responses saved before it was kept
+	Pinned        bool
+}
+
+// authValue returns the saved value of field j of auth type i, requests
+// saved before the type or the field existed don't have it.
+func (r *DBRequest) authValue(i, j int) string {
+	if i >= len(r.Auth.Types) || j >= len(r.Auth.Types[i].Fields) {
+		return ""
+	}
+	return r.Auth.Types[i].Fields[j].Value
+}
+
+// Copy returns a deep copy of the request under a new name, without its
+// responses and not yet saved.
+func (r *DBRequest) Copy(name string) *DBRequest {
+	c := &DBRequest{
+		Name:    name,
+		Method:  r.Method,
+		Url:     r.Url,
+		Query:   slices.Clone(r.Query),
+		Headers: slices.Clone(r.Headers),
+	}
+	c.Body.Selected = r.Body.Selected
+	for _, type_ := range r.Body.Types {
+		c.Body.Types = append(c.Body.Types, &NameValue{type_.Name, type_.Value})
+	}
+	c.Auth.Selected = r.Auth.Selected
+	for _, type_ := range r.Auth.Types {
+		authType := dbAuthType{type_.Type, []*NameValue{}}
+		for _, field := range type_.Fields {
+			authType.Fields = append(authType.Fields, &NameValue{field.Name, field.Value})
+		}
+		c.Auth.Types = append(c.Auth.Types, authType)
+	}
+	return c
 }
 
 type Store struct {
 	conn *sql.DB
+	// encrypts the sensitive auth fields and tokens, nil keeps them plain
+	secrets *secretBox
+	// SQLite has FTS5, the search uses the indexes
+	fts bool
+}
+
+// sealAuth returns a copy of the auth with the sensitive fields encrypted
+func (s *Store) sealAuth(auth dbAuth) dbAuth {
+	sealed := dbAuth{Selected: auth.Selected}
+	for i, type_ := range auth.Types {
+		sealedType := dbAuthType{type_.Type, []*NameValue{}}
+		for j, field := range type_.Fields {
+			value := field.Value
+			if sensitive(i, j) {
+				value = s.secrets.seal(value)
+			}
+			sealedType.Fields = append(sealedType.Fields, &NameValue{field.Name, value})
+		}
+		sealed.Types = append(sealed.Types, sealedType)
+	}
+	return sealed
+}
+
+func (s *Store) openAuth(auth *dbAuth) error {
+	for _, type_ := range auth.Types {
+		for _, field := range type_.Fields {
+			value, err := s.secrets.open(field.Value)
+			if err != nil {
+				return err
+			}
+			field.Value = value
+		}
+	}
+	return nil
 }
 
 func (s *Store) Init() error {
 	var err error
-	s.conn, err = sql.Open("sqlite3", "./requests.db")
+	s.conn, err = sql.Open("sqlite3", "./requests.db?_foreign_keys=on")
 	if err != nil {
 		return err
 	}
@@ -92,23 +199,127 @@ func (s *Store) Init() error {
         size text,
         response_at integer,
         trace_logs text,
-        FOREIGN KEY (request_id) REFERENCES names (id) ON DELETE CASCADE ON UPDATE CASCADE
+        FOREIGN KEY (request_id) REFERENCES requests (id) ON DELETE CASCADE ON UPDATE CASCADE
     );`
 
 	if _, err := s.conn.Exec(responses); err != nil {
 		return err
 	}
 
+	if err := s.migrate(); err != nil {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	if err := s.initSearch(); err != nil {
+		return fmt.Errorf("failed to init search: %w", err)
+	}
+
+	// requests trashed in a previous session can no longer be undone
+	if _, err := s.conn.Exec("DELETE FROM requests WHERE deleted_at IS NOT NULL;"); err != nil {
+		return fmt.Errorf("failed to empty trash: %w", err)
+	}
+
+	return nil
+}
+
+// migrations are applied in order and tracked with PRAGMA user_version,
+// append new ones to the end and never edit an already released one.
+var migrations = []string{
+	// responses referenced a "names" table that never existed, rebuild it
+	// pointing to requests and drop the responses orphaned by that.
+	`CREATE TABLE responses_new (
+        id integer not null primary key autoincrement,
+        request_id integer,
+        request_method text,
+        request_url text,
+		body text,
+        status text,
+        headers text,
+        cookies text,
+        duration text,
+        size text,
+        response_at integer,
+        trace_logs text,
+        FOREIGN KEY (request_id) REFERENCES requests (id) ON DELETE CASCADE ON UPDATE CASCADE
+    );
+	INSERT INTO responses_new SELECT * FROM responses WHERE request_id IN (SELECT id FROM requests);
+	DROP TABLE responses;
+	ALTER TABLE responses_new RENAME TO responses;`,
+	// soft delete so a deleted request can be restored
+	`ALTER TABLE requests ADD COLUMN deleted_at integer;`,
+	// user defined ordering of the requests, existing ones keep creation order
+	`ALTER TABLE requests ADD COLUMN position integer not null default 0;
+	UPDATE requests SET position = id;
+	CREATE TABLE IF NOT EXISTS settings (
+		key text not null primary key,
+		value text not null
+	);`,
+	// OAuth 2.0 tokens by the key of the config that acquired them
+	`CREATE TABLE IF NOT EXISTS oauth_tokens (
+		key text not null primary key,
+		access_token text not null,
+		token_type text not null,
+		refresh_token text not null,
+		scope text not null,
+		expires_at integer not null
+	);`,
+	// the cookie jar, persisted across sessions
+	`CREATE TABLE IF NOT EXISTS cookies (
+		domain text not null,
+		path text not null,
+		name text not null,
+		value text not null,
+		expires integer not null,
+		secure integer not null,
+		http_only integer not null,
+		host_only integer not null,
+		PRIMARY KEY (domain, path, name)
+	);`,
+	// the timing breakdown of the responses
+	`ALTER TABLE responses ADD COLUMN timing text not null default '';`,
+	// what went over the wire
+	`ALTER TABLE responses ADD COLUMN raw text not null default '';`,
+	// the request that produced the response
+	`ALTER TABLE responses ADD COLUMN request text not null default '';`,
+	// pinned responses are never pruned
+	`ALTER TABLE responses ADD COLUMN pinned integer not null default 0;`,
+}
+
+func (s *Store) migrate() error {
+	var version int
+	if err := s.conn.QueryRow("PRAGMA user_version;").Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.conn.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		// PRAGMA does not accept bound parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d;", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (s *Store) GetRequests() ([]DBRequest, error) {
-	requestRows, err := s.conn.Query("SELECT * FROM requests")
+	requestRows, err := s.conn.Query(`SELECT id, name, method, url, body, auth, query, headers, position
+    FROM requests WHERE deleted_at IS NULL ORDER BY position, id`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query requests: %w", err)
 	}
 	defer requestRows.Close()
 
+	requests := []*DBRequest{}
 	requestsMap := map[int64]*DBRequest{}
 	for requestRows.Next() {
 		var (
@@ -118,7 +329,7 @@ func (s *Store) GetRequests() ([]DBRequest, error) {
 			queryJSON   string
 			headersJSON string
 		)
-		err := requestRows.Scan(&r.ID, &r.Name, &r.Method, &r.Url, &bodyJSON, &authJSON, &queryJSON, &headersJSON)
+		err := requestRows.Scan(&r.ID, &r.Name, &r.Method, &r.Url, &bodyJSON, &authJSON, &queryJSON, &headersJSON, &r.Position)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan requests: %w", err)
 		}
@@ -128,16 +339,22 @@ func (s *Store) GetRequests() ([]DBRequest, error) {
 		if err := json.Unmarshal([]byte(authJSON), &r.Auth); err != nil {
 			return nil, fmt.Errorf("failed to parse Auth: %w", err)
 		}
+		if err := s.openAuth(&r.Auth); err != nil {
+			return nil, fmt.Errorf("failed to decrypt Auth of %q: %w", r.Name, err)
+		}
 		if err := json.Unmarshal([]byte(queryJSON), &r.Query); err != nil {
 			return nil, fmt.Errorf("failed to parse Query: %w", err)
 		}
 		if err := json.Unmarshal([]byte(headersJSON), &r.Headers); err != nil {
 			return nil, fmt.Errorf("failed to parse Headers: %w", err)
 		}
+		requests = append(requests, &r)
 		requestsMap[r.ID] = &r
 	}
 
-	responseRows, err := s.conn.Query("SELECT * FROM responses ORDER BY response_at DESC")
+	responseRows, err := s.conn.Query(`SELECT id, request_id, request_method, request_url, body, status,
+    headers, cookies, duration, size, response_at, trace_logs, timing, raw, request, pinned
+    FROM responses ORDER BY response_at DESC`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query responses: %w", err)
 	}
@@ -148,13 +365,15 @@ func (s *Store) GetRequests() ([]DBRequest, error) {
 			r           DBResponse
 			HeadersJSON string
 			CookiesJSON string
+			TimingJSON  string
+			RequestJSON string
 			unixTime    int64
 		)
 		err := responseRows.Scan(
 			&r.ID, &r.RequestID, &r.RequestMethod, &r.RequestUrl,
 			&r.Body, &r.Status, &HeadersJSON,
 			&CookiesJSON, &r.Duration, &r.Size,
-			&unixTime, &r.TraceLogs,
+			&unixTime, &r.TraceLogs, &TimingJSON, &r.Raw, &RequestJSON, &r.Pinned,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan response: %w", err)
@@ -165,15 +384,29 @@ func (s *Store) GetRequests() ([]DBRequest, error) {
 		if err := json.Unmarshal([]byte(CookiesJSON), &r.Cookies); err != nil {
 			return nil, fmt.Errorf("failed to parse Response Cookies: %w", err)
 		}
+		// responses saved before the timing was kept don't have it
+		if TimingJSON != "" {
+			if err := json.Unmarshal([]byte(TimingJSON), &r.Timing); err != nil {
+				return nil, fmt.Errorf("failed to parse Response Timing: %w", err)
+			}
+		}
+		if RequestJSON != "" {
+			if err := json.Unmarshal([]byte(RequestJSON), &r.Request); err != nil {
+				return nil, fmt.Errorf("failed to parse Response Request: %w", err)
+			}
+		}
 
 		r.ResponseAt = time.UnixMilli(unixTime)
-		request := requestsMap[r.RequestID]
+		request, ok := requestsMap[r.RequestID]
+		if !ok { // belongs to a trashed request
+			continue
+		}
 		request.Responses = append(request.Responses, r)
 	}
 
 	result := []DBRequest{}
-	for _, name := range requestsMap {
-		result = append(result, *name)
+	for _, r := range requests {
+		result = append(result, *r)
 	}
 
 	return result, nil
@@ -184,7 +417,7 @@ func (s *Store) SaveRequest(r *DBRequest) error {
 	if err != nil {
 		return fmt.Errorf("failed to serialize Body: %w", err)
 	}
-	authJSON, err := json.Marshal(r.Auth)
+	authJSON, err := json.Marshal(s.sealAuth(r.Auth))
 	if err != nil {
 		return fmt.Errorf("failed to serialize Auth: %w", err)
 	}
@@ -197,13 +430,11 @@ func (s *Store) SaveRequest(r *DBRequest) error {
 		return fmt.Errorf("failed to serialize Headers: %w", err)
 	}
 	if r.ID == 0 {
-		requestQuery := `INSERT INTO requests (name, method, url, body, auth, query, headers) VALUES (?, ?, ?, ?, ?, ?, ?);`
-		result, err := s.conn.Exec(requestQuery, r.Name, r.Method, r.Url, string(bodyJSON), string(authJSON), string(queryJSON), string(headersJSON))
-		if err != nil {
-			return err
-		}
-		r.ID, err = result.LastInsertId()
-		if err != nil {
+		requestQuery := `INSERT INTO requests (name, method, url, body, auth, query, headers, position)
+        VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM requests))
+        RETURNING id, position;`
+		row := s.conn.QueryRow(requestQuery, r.Name, r.Method, r.Url, string(bodyJSON), string(authJSON), string(queryJSON), string(headersJSON))
+		if err := row.Scan(&r.ID, &r.Position); err != nil {
 			return err
 		}
 	} else {
@@ -229,14 +460,24 @@ func (s *Store) SaveResponse(r *DBResponse) error {
 	if err != nil {
 		return fmt.Errorf("failed to serialize Response Cookies: %w", err)
 	}
+	timingJSON, err := json.Marshal(r.Timing)
+	if err != nil {
+		return fmt.Errorf("failed to serialize Response Timing: %w", err)
+	}
+	requestJSON := []byte{}
+	if r.Request != nil {
+		if requestJSON, err = json.Marshal(r.Request); err != nil {
+			return fmt.Errorf("failed to serialize Response Request: %w", err)
+		}
+	}
 
-	responseQuery := `INSERT INTO responses (request_id, request_method, request_url, body, status, headers, cookies, duration, size, response_at, trace_logs)
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	responseQuery := `INSERT INTO responses (request_id, request_method, request_url, body, status, headers, cookies, duration, size, response_at, trace_logs, timing, raw, request)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 
 	result, err := s.conn.Exec(
 		responseQuery, r.RequestID, r.RequestMethod, r.RequestUrl,
 		r.Body, r.Status, string(headersJSON), string(cookiesJSON),
-		r.Duration, r.Size, r.ResponseAt.UnixMilli(), r.TraceLogs,
+		r.Duration, r.Size, r.ResponseAt.UnixMilli(), r.TraceLogs, string(timingJSON), r.Raw, string(requestJSON),
 	)
 	if err != nil {
 		return err
@@ -249,18 +490,500 @@ func (s *Store) SaveResponse(r *DBResponse) error {
 	return nil
 }
 
+// DeleteRequest moves the request to the trash, it gets permanently deleted
+// (along with its responses) on the next Init unless restored before.
 func (s *Store) DeleteRequest(r *DBRequest) error {
-	query := `DELETE FROM requests WHERE ID=$ID;`
+	query := `UPDATE requests SET deleted_at=? WHERE id=?;`
+	if _, err := s.conn.Exec(query, time.Now().UnixMilli(), r.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetPositions persists the order of the given requests as their position.
+func (s *Store) SetPositions(requests []*DBRequest) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	for i, r := range requests {
+		if _, err := tx.Exec(`UPDATE requests SET position=? WHERE id=?;`, i+1, r.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		r.Position = i + 1
+	}
+	return tx.Commit()
+}
+
+func (s *Store) GetSetting(key, fallback string) (string, error) {
+	var value string
+	err := s.conn.QueryRow(`SELECT value FROM settings WHERE key=?;`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return fallback, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func (s *Store) SetSetting(key, value string) error {
+	query := `INSERT INTO settings (key, value) VALUES (?, ?)
+    ON CONFLICT(key) DO UPDATE SET value=excluded.value;`
+	if _, err := s.conn.Exec(query, key, value); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) RestoreRequest(r *DBRequest) error {
+	query := `UPDATE requests SET deleted_at=NULL WHERE id=?;`
 	if _, err := s.conn.Exec(query, r.ID); err != nil {
 		return err
 	}
 	return nil
 }
 
-// func jsonToMarshal(j NameValue) (string, error) {
-// 	m, err := json.Marshal(j)
-// 	if err != nil {
-// 		return "", fmt.Errorf("failed to serialize: %w", err)
-// 	}
-// 	return string(m), nil
-// }
+func (s *Store) scanToken(row interface{ Scan(...any) error }) (string, oauthToken, error) {
+	var (
+		key       string
+		t         oauthToken
+		expiresAt int64
+	)
+	if err := row.Scan(&key, &t.AccessToken, &t.TokenType, &t.RefreshToken, &t.Scope, &expiresAt); err != nil {
+		return "", t, err
+	}
+	if expiresAt != 0 {
+		t.ExpiresAt = time.UnixMilli(expiresAt)
+	}
+	var err error
+	if t.AccessToken, err = s.secrets.open(t.AccessToken); err != nil {
+		return "", t, err
+	}
+	if t.RefreshToken, err = s.secrets.open(t.RefreshToken); err != nil {
+		return "", t, err
+	}
+	return key, t, nil
+}
+
+// GetToken returns the OAuth 2.0 token stored for the key, ok is false if none.
+func (s *Store) GetToken(key string) (token oauthToken, ok bool, err error) {
+	row := s.conn.QueryRow(`SELECT key, access_token, token_type, refresh_token, scope, expires_at
+    FROM oauth_tokens WHERE key=?;`, key)
+	_, token, err = s.scanToken(row)
+	if err == sql.ErrNoRows {
+		return token, false, nil
+	}
+	if err != nil {
+		return token, false, fmt.Errorf("failed to get token: %w", err)
+	}
+	return token, true, nil
+}
+
+func (s *Store) GetTokens() (map[string]oauthToken, error) {
+	rows, err := s.conn.Query(`SELECT key, access_token, token_type, refresh_token, scope, expires_at FROM oauth_tokens;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tokens: %w", err)
+	}
+	defer rows.Close()
+	tokens := map[string]oauthToken{}
+	for rows.Next() {
+		key, token, err := s.scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		tokens[key] = token
+	}
+	return tokens, rows.Err()
+}
+
+func (s *Store) SaveToken(key string, t oauthToken) error {
+	var expiresAt int64
+	if !t.ExpiresAt.IsZero() {
+		expiresAt = t.ExpiresAt.UnixMilli()
+	}
+	query := `INSERT INTO oauth_tokens (key, access_token, token_type, refresh_token, scope, expires_at)
+    VALUES (?, ?, ?, ?, ?, ?)
+    ON CONFLICT(key) DO UPDATE SET access_token=excluded.access_token, token_type=excluded.token_type,
+    refresh_token=excluded.refresh_token, scope=excluded.scope, expires_at=excluded.expires_at;`
+	access, refresh := s.secrets.seal(t.AccessToken), s.secrets.seal(t.RefreshToken)
+	if _, err := s.conn.Exec(query, key, access, t.TokenType, refresh, t.Scope, expiresAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) GetCookies() ([]storedCookie, error) {
+	rows, err := s.conn.Query(`SELECT domain, path, name, value, expires, secure, http_only, host_only FROM cookies;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cookies: %w", err)
+	}
+	defer rows.Close()
+	var cookies []storedCookie
+	for rows.Next() {
+		var (
+			c       storedCookie
+			expires int64
+		)
+		if err := rows.Scan(&c.Domain, &c.Path, &c.Name, &c.Value, &expires, &c.Secure, &c.HttpOnly, &c.HostOnly); err != nil {
+			return nil, fmt.Errorf("failed to scan cookie: %w", err)
+		}
+		if expires != 0 {
+			c.Expires = time.UnixMilli(expires)
+		}
+		// session cookies can be as good as a password
+		if c.Value, err = s.secrets.open(c.Value); err != nil {
+			return nil, fmt.Errorf("failed to decrypt cookie %q: %w", c.Name, err)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, rows.Err()
+}
+
+func (s *Store) SaveCookie(c storedCookie) error {
+	var expires int64
+	if !c.Expires.IsZero() {
+		expires = c.Expires.UnixMilli()
+	}
+	query := `INSERT INTO cookies (domain, path, name, value, expires, secure, http_only, host_only)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(domain, path, name) DO UPDATE SET value=excluded.value, expires=excluded.expires,
+    secure=excluded.secure, http_only=excluded.http_only, host_only=excluded.host_only;`
+	if _, err := s.conn.Exec(query, c.Domain, c.Path, c.Name, s.secrets.seal(c.Value), expires, c.Secure, c.HttpOnly, c.HostOnly); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) DeleteCookie(c storedCookie) error {
+	query := `DELETE FROM cookies WHERE domain=? AND path=? AND name=?;`
+	if _, err := s.conn.Exec(query, c.Domain, c.Path, c.Name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteCookies deletes the cookies of the domain
+func (s *Store) DeleteCookies(domain string) error {
+	if _, err := s.conn.Exec(`DELETE FROM cookies WHERE domain=?;`, domain); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) DeleteResponse(id int64) error {
+	if _, err := s.conn.Exec(`DELETE FROM responses WHERE id=?;`, id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) PinResponse(id int64, pinned bool) error {
+	if _, err := s.conn.Exec(`UPDATE responses SET pinned=? WHERE id=?;`, pinned, id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// vacuumThreshold is the free space that makes a prune VACUUM the database
+const vacuumThreshold = 8 << 20
+
+// PruneResponses deletes the responses out of the retention limits, except
+// the pinned ones, and returns their ids.
+func (s *Store) PruneResponses(r retention) ([]int64, error) {
+	var pruned []int64
+	collect := func(query string, args ...any) error {
+		rows, err := s.conn.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			if !slices.Contains(pruned, id) {
+				pruned = append(pruned, id)
+			}
+		}
+		return rows.Err()
+	}
+	if r.KeepLast > 0 {
+		query := `SELECT id FROM (
+		SELECT id, pinned, ROW_NUMBER() OVER (PARTITION BY request_id ORDER BY response_at DESC) AS n FROM responses
+    ) WHERE n > ? AND pinned = 0;`
+		if err := collect(query, r.KeepLast); err != nil {
+			return nil, fmt.Errorf("failed to find the responses to keep: %w", err)
+		}
+	}
+	if r.MaxAgeDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -r.MaxAgeDays).UnixMilli()
+		if err := collect(`SELECT id FROM responses WHERE response_at < ? AND pinned = 0;`, cutoff); err != nil {
+			return nil, fmt.Errorf("failed to find the old responses: %w", err)
+		}
+	}
+	if err := s.deleteResponses(pruned); err != nil {
+		return nil, err
+	}
+
+	if r.MaxSizeMB > 0 {
+		used, _, err := s.pages()
+		if err != nil {
+			return nil, err
+		}
+		// the oldest go first until what they take covers the excess
+		excess := used - int64(r.MaxSizeMB)<<20
+		rows, err := s.conn.Query(`SELECT id, length(body) + length(headers) + length(cookies) + length(trace_logs)
+    + length(timing) + length(raw) + length(request) FROM responses WHERE pinned = 0 ORDER BY response_at;`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the responses to fit the size: %w", err)
+		}
+		var bySize []int64
+		for excess > 0 && rows.Next() {
+			var id, size int64
+			if err := rows.Scan(&id, &size); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			bySize = append(bySize, id)
+			excess -= size
+		}
+		rows.Close()
+		if err := s.deleteResponses(bySize); err != nil {
+			return nil, err
+		}
+		pruned = append(pruned, bySize...)
+	}
+
+	_, free, err := s.pages()
+	if err != nil {
+		return nil, err
+	}
+	if free >= vacuumThreshold {
+		if _, err := s.conn.Exec(`VACUUM;`); err != nil {
+			return nil, fmt.Errorf("failed to vacuum: %w", err)
+		}
+	}
+	return pruned, nil
+}
+
+func (s *Store) deleteResponses(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM responses WHERE id=?;`, id); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete response: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// pages returns the bytes used by the database and the free ones
+func (s *Store) pages() (used, free int64, err error) {
+	var count, freelist, size int64
+	for pragma, value := range map[string]*int64{"page_count": &count, "freelist_count": &freelist, "page_size": &size} {
+		if err := s.conn.QueryRow(`PRAGMA ` + pragma + `;`).Scan(value); err != nil {
+			return 0, 0, fmt.Errorf("failed to read %s: %w", pragma, err)
+		}
+	}
+	return (count - freelist) * size, freelist * size, nil
+}
+
+// searchText is what is searched in a request, the headers and the body of
+// the selected type are taken out of their JSON. row is "new.", "old." or ""
+// for the table.
+func searchText(row string) []string {
+	return []string{
+		`coalesce(` + row + `name, '')`,
+		`coalesce(` + row + `url, '')`,
+		`coalesce((SELECT group_concat(json_extract(value, '$.Name') || ': ' || json_extract(value, '$.Value'), char(10))
+		FROM json_each(` + row + `headers)), '')`,
+		`coalesce(json_extract(` + row + `body, '$.Types[' || json_extract(` + row + `body, '$.Selected') || '].Value'), '')`,
+	}
+}
+
+// the requests index keeps the text it was given, the responses one reads
+// it from the table so only the index is stored. Both follow the tables
+// through the triggers.
+var searchTables = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS search_requests USING fts5(name, url, headers, body);`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS search_responses USING fts5(
+		status, body, content='responses', content_rowid='id'
+	);`,
+}
+
+var searchTriggers = map[string]string{
+	"search_requests_insert": `AFTER INSERT ON requests BEGIN
+		INSERT INTO search_requests(rowid, name, url, headers, body) VALUES (new.id, ` + strings.Join(searchText("new."), ", ") + `);
+	END;`,
+	"search_requests_delete": `AFTER DELETE ON requests BEGIN
+		DELETE FROM search_requests WHERE rowid = old.id;
+	END;`,
+	"search_requests_update": `AFTER UPDATE OF name, url, headers, body ON requests BEGIN
+		DELETE FROM search_requests WHERE rowid = old.id;
+		INSERT INTO search_requests(rowid, name, url, headers, body) VALUES (new.id, ` + strings.Join(searchText("new."), ", ") + `);
+	END;`,
+	"search_responses_insert": `AFTER INSERT ON responses BEGIN
+		INSERT INTO search_responses(rowid, status, body) VALUES (new.id, new.status, new.body);
+	END;`,
+	"search_responses_delete": `AFTER DELETE ON responses BEGIN
+		INSERT INTO search_responses(search_responses, rowid, status, body) VALUES ('delete', old.id, old.status, old.body);
+	END;`,
+}
+
+// initSearch sets up the full-text search when SQLite has FTS5 (built with
+// -tags sqlite_fts5), the search falls back to LIKE otherwise. A database
+// opened by a build without FTS5 loses the triggers, since they would fail
+// every write, and the indexes are rebuilt once FTS5 is back.
+func (s *Store) initSearch() error {
+	if err := s.conn.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5');`).Scan(&s.fts); err != nil {
+		return err
+	}
+	if !s.fts {
+		for name := range searchTriggers {
+			if _, err := s.conn.Exec(`DROP TRIGGER IF EXISTS ` + name + `;`); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var triggers int
+	if err := s.conn.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'search_%';`).Scan(&triggers); err != nil {
+		return err
+	}
+	if triggers == len(searchTriggers) {
+		return nil
+	}
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	statements := slices.Clone(searchTables)
+	for name, trigger := range searchTriggers {
+		statements = append(statements, `CREATE TRIGGER IF NOT EXISTS `+name+` `+trigger)
+	}
+	statements = append(statements,
+		`DELETE FROM search_requests;`,
+		`INSERT INTO search_requests(rowid, name, url, headers, body) SELECT id, `+strings.Join(searchText(""), ", ")+` FROM requests;`,
+		`INSERT INTO search_responses(search_responses) VALUES ('rebuild');`)
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SearchResult is a request, or one of its responses when ResponseID isn't
+// 0, with the matched text between snippetOpen and snippetClose.
+type SearchResult struct {
+	RequestID  int64
+	ResponseID int64
+	Snippet    string
+}
+
+// Search finds the requests by name, URL, headers and body and the
+// responses by status and body, every word of the query has to match.
+// The requests come first, then the responses, each limited to limit.
+func (s *Store) Search(query string, limit int) ([]SearchResult, error) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	if s.fts {
+		return s.searchFTS(words, limit)
+	}
+	return s.searchLike(words, limit)
+}
+
+func (s *Store) searchFTS(words []string, limit int) ([]SearchResult, error) {
+	// every word is a prefix, quoted so the query syntax doesn't apply
+	var terms []string
+	for _, word := range words {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	match := strings.Join(terms, " ")
+	var results []SearchResult
+	rows, err := s.conn.Query(`SELECT requests.id, 0, snippet(search_requests, -1, ?, ?, '…', 12)
+    FROM search_requests JOIN requests ON requests.id = search_requests.rowid
+    WHERE search_requests MATCH ? AND requests.deleted_at IS NULL ORDER BY rank LIMIT ?;`,
+		snippetOpen, snippetClose, match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search requests: %w", err)
+	}
+	if results, err = scanSearchResults(rows, results); err != nil {
+		return nil, err
+	}
+	rows, err = s.conn.Query(`SELECT responses.request_id, responses.id, snippet(search_responses, -1, ?, ?, '…', 12)
+    FROM search_responses JOIN responses ON responses.id = search_responses.rowid
+    JOIN requests ON requests.id = responses.request_id
+    WHERE search_responses MATCH ? AND requests.deleted_at IS NULL ORDER BY rank LIMIT ?;`,
+		snippetOpen, snippetClose, match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search responses: %w", err)
+	}
+	return scanSearchResults(rows, results)
+}
+
+// searchLike scans the tables, the snippets are made from the whole text
+func (s *Store) searchLike(words []string, limit int) ([]SearchResult, error) {
+	var patterns []any
+	likes := func(text string) string {
+		var conditions []string
+		for range words {
+			conditions = append(conditions, text+` LIKE ? ESCAPE '\'`)
+		}
+		return strings.Join(conditions, " AND ")
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	for _, word := range words {
+		patterns = append(patterns, "%"+escape.Replace(word)+"%")
+	}
+	requestText := strings.Join(searchText(""), " || char(10) || ")
+	const responseText = `coalesce(responses.status, '') || char(10) || coalesce(responses.body, '')`
+	var results []SearchResult
+	rows, err := s.conn.Query(`SELECT id, 0, `+requestText+` FROM requests
+    WHERE deleted_at IS NULL AND `+likes(requestText)+` ORDER BY position, id LIMIT ?;`,
+		append(patterns, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search requests: %w", err)
+	}
+	if results, err = scanSearchResults(rows, results); err != nil {
+		return nil, err
+	}
+	rows, err = s.conn.Query(`SELECT responses.request_id, responses.id, `+responseText+`
+    FROM responses JOIN requests ON requests.id = responses.request_id
+    WHERE requests.deleted_at IS NULL AND `+likes(responseText)+` ORDER BY responses.response_at DESC LIMIT ?;`,
+		append(patterns, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search responses: %w", err)
+	}
+	if results, err = scanSearchResults(rows, results); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Snippet = likeSnippet(results[i].Snippet, words)
+	}
+	return results, nil
+}
+
+func scanSearchResults(rows *sql.Rows, results []SearchResult) ([]SearchResult, error) {
+	defer rows.Close()
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.RequestID, &r.ResponseID, &r.Snippet); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+)
+
+// newTestStore opens a store in a temporary directory, the database is
+// created in the working directory.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	s := new(Store)
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.conn.Close() })
+	return s
+}
+
+// a database of the first release: responses reference a "names" table
+// that never existed and some belong to requests deleted since
+func TestMigrateLegacySchema(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	legacy, err := sql.Open("sqlite3", "./requests.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(dbBody{Types: []*NameValue{{"No body", ""}}})
+	auth, _ := json.Marshal(dbAuth{Types: []dbAuthType{{"No Auth", []*NameValue{}}}})
+	for _, statement := range []string{
+		`CREATE TABLE requests (id integer not null primary key autoincrement, name text not null, method text not null,
+			url text, body text, auth text, query text, headers text);`,
+		`CREATE TABLE responses (id integer not null primary key autoincrement, request_id integer, request_method text,
+			request_url text, body text, status text, headers text, cookies text, duration text, size text,
+			response_at integer, trace_logs text,
+			FOREIGN KEY (request_id) REFERENCES names (id) ON DELETE CASCADE ON UPDATE CASCADE);`,
+		`INSERT INTO requests (name, method, url, body, auth, query, headers) VALUES
+			('list', 'GET', 'https://example.com', '` + string(body) + `', '` + string(auth) + `', 'null', 'null'),
+			('create', 'POST', 'https://example.com', '` + string(body) + `', '` + string(auth) + `', 'null', 'null');`,
+		`INSERT INTO responses (request_id, request_method, request_url, body, status, headers, cookies, duration, size, response_at, trace_logs) VALUES
+			(1, 'GET', 'https://example.com', 'first', '200 OK', 'null', 'null', '1ms', '5', 1000, ''),
+			(1, 'GET', 'https://example.com', 'second', '200 OK', 'null', 'null', '1ms', '6', 2000, ''),
+			(7, 'GET', 'https://example.com', 'orphaned', '200 OK', 'null', 'null', '1ms', '8', 3000, ''),
+			(2, 'POST', 'https://example.com', 'created', '201 Created', 'null', 'null', '1ms', '7', 4000, '');`,
+	} {
+		if _, err := legacy.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy.Close()
+
+	s := new(Store)
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.conn.Close()
+
+	var version int
+	if err := s.conn.QueryRow("PRAGMA user_version;").Scan(&version); err != nil || version != len(migrations) {
+		t.Errorf("user_version = %d, %v, want %d", version, err, len(migrations))
+	}
+	var parent string
+	if err := s.conn.QueryRow(`SELECT "table" FROM pragma_foreign_key_list('responses');`).Scan(&parent); err != nil || parent != "requests" {
+		t.Errorf("responses reference %q, %v", parent, err)
+	}
+	requests, err := s.GetRequests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bodies := map[string][]string{}
+	for _, r := range requests {
+		for _, res := range r.Responses {
+			bodies[r.Name] = append(bodies[r.Name], res.Body)
+		}
+	}
+	if len(requests) != 2 || !slices.Equal(bodies["list"], []string{"second", "first"}) || !slices.Equal(bodies["create"], []string{"created"}) {
+		t.Errorf("after the migration: %d requests, responses %v", len(requests), bodies)
+	}
+	var orphans int
+	if err := s.conn.QueryRow(`SELECT count(*) FROM responses WHERE body = 'orphaned';`).Scan(&orphans); err != nil || orphans != 0 {
+		t.Errorf("%d orphaned responses left, %v", orphans, err)
+	}
+
+	// the foreign key works now
+	if err := s.DeleteRequest(&requests[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.conn.Exec("DELETE FROM requests WHERE deleted_at IS NOT NULL;"); err != nil {
+		t.Fatal(err)
+	}
+	var left int
+	if err := s.conn.QueryRow(`SELECT count(*) FROM responses;`).Scan(&left); err != nil || left != len(requests[1].Responses) {
+		t.Errorf("%d responses left after deleting %s, want %d", left, requests[0].Name, len(requests[1].Responses))
+	}
+
+	// and opening it again migrates nothing
+	s.conn.Close()
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a failing store is reported in the status line, the app keeps running and
+// the deleted request stays in the trash
+func TestStoreErrorsReported(t *testing.T) {
+	m := newTestModel(t)
+	addRequest(&m, newRequest(m))
+	addRequest(&m, newRequest(m))
+	view, mode = requestsView, normal
+	m = press(t, m, runes("d"))
+	m.db.conn.Close()
+	m.requests.cursor = 0
+
+	for _, key := range []string{"s", "J", "u"} {
+		m.err = nil
+		m = press(t, m, runes(key))
+		if m.err == nil {
+			t.Errorf("%s: no error shown", key)
+		}
+	}
+	if len(m.trash) != 1 || len(m.requests.items) != 2 {
+		t.Errorf("%d trashed, %d requests, want 1 and 2", len(m.trash), len(m.requests.items))
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/paginator"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+const tabNameWidth = 15
+
+// tabState is everything the right panel shows for a request, the model
+// embeds the one of the active tab.
+type tabState struct {
+	method           selectField
+	url              textinput.Model
+	body             body
+	auth             auth
+	queryParams      inputFields
+	headers          inputFields
+	requestPaginator paginator.Model
+
+	history           selectField
+	resBody           viewport.Model
+	resHeaders        table.Model
+	resCookies        table.Model
+	resLogs           string
+	resTiming         Timing
+	resRaw            viewport.Model
+	responsePaginator paginator.Model
+}
+
+type requestTab struct {
+	request *DBRequest
+	state   tabState
+}
+
+// clone copies the state so editing one tab never changes another. The
+// viewports and tables replace their content on SetContent/SetRows, but the
+// inputs edit their runes in place and the textarea shares its viewport
+// through a pointer, so these are re-created from their values.
+func (s tabState) clone() tabState {
+	c := s
+	c.url = cloneInput(s.url)
+	c.body.options = slices.Clone(s.body.options)
+	c.body.field = makeBody().field
+	c.body.field.SetHeight(s.body.field.Height())
+	c.body.field.SetValue(s.body.field.Value())
+	c.auth.options = nil
+	for _, option := range s.auth.options {
+		authType := *option
+		authType.fields = cloneInputs(option.fields)
+		c.auth.options = append(c.auth.options, &authType)
+	}
+	c.queryParams.fields = cloneInputs(s.queryParams.fields)
+	c.headers.fields = cloneInputs(s.headers.fields)
+	return c
+}
+
+// cloneInput gives the copy of the input its own runes, the cursor stays
+func cloneInput(input textinput.Model) textinput.Model {
+	input.SetValue(input.Value())
+	return input
+}
+
+func cloneInputs(inputs []textinput.Model) []textinput.Model {
+	clones := make([]textinput.Model, len(inputs))
+	for i, input := range inputs {
+		clones[i] = cloneInput(input)
+	}
+	return clones
+}
+
+// dirty reports if the state has changes not saved in the request
+func (s *tabState) dirty(r *DBRequest) bool {
+	if r.ID == 0 {
+		return true
+	}
+	if s.method.options[s.method.cursor] != r.Method || s.url.Value() != r.Url {
+		return true
+	}
+	if s.body.cursor != r.Body.Selected || s.auth.cursor != r.Auth.Selected {
+		return true
+	}
+	for i, option := range s.body.options {
+		value := option.value
+		if i == s.body.cursor && s.body.cursor != 0 {
+			value = s.body.field.Value()
+		}
+		if i < len(r.Body.Types) && r.Body.Types[i].Value != value {
+			return true
+		}
+	}
+	for i, option := range s.auth.options {
+		for j, field := range option.fields {
+			if field.Value() != r.authValue(i, j) {
+				return true
+			}
+		}
+	}
+	return !slices.Equal(nameValues(s.queryParams.fields), r.Query) ||
+		!slices.Equal(nameValues(s.headers.fields), r.Headers)
+}
+
+func tabIndex(m *model, r *DBRequest) int {
+	return slices.IndexFunc(m.tabs, func(t requestTab) bool { return t.request == r })
+}
+
+// unsaved reports if the request is open in a tab with unsaved changes
+func unsaved(m *model, r *DBRequest) bool {
+	switch i := tabIndex(m, r); {
+	case i < 0:
+		return false
+	case i == m.activeTab:
+		return m.tabState.dirty(r)
+	default:
+		return m.tabs[i].state.dirty(r)
+	}
+}
+
+func unsavedTabs(m *model) int {
+	count := 0
+	for _, tab := range m.tabs {
+		if unsaved(m, tab.request) {
+			count++
+		}
+	}
+	return count
+}
+
+// saveTabs saves every tab with unsaved changes
+func saveTabs(m *model) {
+	active := m.activeTab
+	for i, tab := range m.tabs {
+		if unsaved(m, tab.request) {
+			switchTab(m, i)
+			saveRequest(*m)
+		}
+	}
+	switchTab(m, active)
+}
+
+// syncCursor keeps the sidebar selection on the request of the active tab
+func syncCursor(m *model) {
+	if len(m.tabs) == 0 {
+		return
+	}
+	if i := slices.Index(m.requests.items, m.tabs[m.activeTab].request); i >= 0 {
+		m.requests.cursor = i
+	}
+}
+
+// openTab shows the request in a new tab, or switches to its tab if open
+func openTab(m *model, r *DBRequest) {
+	if i := tabIndex(m, r); i >= 0 {
+		switchTab(m, i)
+		return
+	}
+	if len(m.tabs) > 0 {
+		m.tabs[m.activeTab].state = m.tabState.clone()
+	}
+	setUIRequest(m, r)
+	m.tabs = append(m.tabs, requestTab{request: r})
+	m.activeTab = len(m.tabs) - 1
+	syncCursor(m)
+}
+
+// showRequest is what selecting a request in the sidebar does, it replaces
+// the active tab unless it has unsaved changes, then a new tab is opened.
+func showRequest(m *model, r *DBRequest) {
+	if len(m.tabs) == 0 || tabIndex(m, r) >= 0 || m.tabState.dirty(m.tabs[m.activeTab].request) {
+		openTab(m, r)
+		return
+	}
+	m.tabs[m.activeTab].request = r
+	setUIRequest(m, r)
+	syncCursor(m)
+}
+
+func switchTab(m *model, i int) {
+	if i == m.activeTab || i < 0 || i >= len(m.tabs) {
+		return
+	}
+	m.tabs[m.activeTab].state = m.tabState.clone()
+	m.tabState = m.tabs[i].state
+	m.activeTab = i
+	// the terminal may have been resized since the tab was left
+	if m.width > 0 {
+		resize(m)
+	}
+	syncCursor(m)
+}
+
+// closeTab drops the tab and its unsaved changes
+func closeTab(m *model, i int) {
+	m.tabs = slices.Delete(m.tabs, i, i+1)
+	switch {
+	case len(m.tabs) == 0:
+		m.activeTab = 0
+		return
+	case i < m.activeTab:
+		m.activeTab--
+		return
+	case i > m.activeTab:
+		return
+	}
+	m.activeTab = min(i, len(m.tabs)-1)
+	m.tabState = m.tabs[m.activeTab].state
+	if m.width > 0 {
+		resize(m)
+	}
+	syncCursor(m)
+}
+
+// applyResponse shows a response in the state of the tab that sent it
+func applyResponse(s *tabState, res DBResponse) {
+	s.history.cursor = 0
+	s.resBody.SetContent(res.Body)
+	s.resLogs = res.TraceLogs
+	s.resTiming = res.Timing
+	setRawContent(&s.resRaw, res.Raw)
+	setTableRows(&s.resHeaders, res.Headers)
+	setCookieRows(&s.resCookies, res.Cookies)
+}
+
+// renderTabs returns the rendered tabs, joined by a space in the tab bar
+func renderTabs(m model) []string {
+	var tabs []string
+	for i, tab := range m.tabs {
+		name := tab.request.Name
+		if i == m.activeTab && view == requestsView && mode == insert {
+			name = m.names[m.requests.cursor].Value()
+		}
+		if len([]rune(name)) > tabNameWidth {
+			name = string([]rune(name)[:tabNameWidth-1]) + "…"
+		}
+		if unsaved(&m, tab.request) {
+			name += " ●"
+		}
+		style := secondary.Padding(0, 1)
+		if i == m.activeTab {
+			style = focused.Padding(0, 1)
+		}
+		tabs = append(tabs, style.Render(name))
+	}
+	return tabs
+}
+
+func renderTabBar(tabs []string) string {
+	return lg.NewStyle().MaxWidth(rightPanelWidth + 2).Render(strings.Join(tabs, " "))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// closing a tab with unsaved changes asks first, the changes are saved,
+// dropped or the tab is kept open
+func TestCloseUnsavedTab(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   tea.KeyMsg
+		tabs  int
+		saved bool
+	}{
+		{"save", tea.KeyMsg{Type: tea.KeyCtrlS}, 1, true},
+		{"discard", runes("y"), 1, false},
+		{"cancel", runes("n"), 2, false},
+	}
+	for _, tt := range tests {
+		m := newTestModel(t)
+		addRequest(&m, newRequest(m))
+		view, mode = requestsView, normal
+		req := m.tabs[m.activeTab].request
+		m.url.SetValue("http://localhost/edited")
+
+		m = press(t, m, runes("x"))
+		if !m.confirmClose || len(m.tabs) != 2 {
+			t.Fatalf("%s: the tab was closed without asking", tt.name)
+		}
+		m = press(t, m, tt.key)
+		if m.confirmClose || len(m.tabs) != tt.tabs {
+			t.Errorf("%s: %d tabs open, want %d", tt.name, len(m.tabs), tt.tabs)
+		}
+		if saved := req.Url == "http://localhost/edited"; saved != tt.saved {
+			t.Errorf("%s: saved %v, want %v", tt.name, saved, tt.saved)
+		}
+	}
+
+	// a tab without changes is closed at once
+	m := newTestModel(t)
+	addRequest(&m, newRequest(m))
+	view, mode = requestsView, normal
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyCtrlS})
+	m = press(t, m, runes("x"))
+	if m.confirmClose || len(m.tabs) != 1 {
+		t.Errorf("the saved tab was not closed: %d tabs, prompt %v", len(m.tabs), m.confirmClose)
+	}
+}
+
+// editing a field after the state was cloned leaves the clone as it was,
+// the inputs edit their runes in place
+func TestCloneTabState(t *testing.T) {
+	m := newTestModel(t)
+	m.url.SetValue("http://localhost/orders")
+	m.headers.fields[0].SetValue("X-Request")
+	m.body.field.SetValue("{\"id\": 1}")
+	c := m.tabState.clone()
+
+	backspace := tea.KeyMsg{Type: tea.KeyBackspace}
+	m.url.Focus()
+	m.url.SetCursor(1)
+	m.url, _ = m.url.Update(backspace)
+	m.headers.fields[0].Focus()
+	m.headers.fields[0].SetCursor(1)
+	m.headers.fields[0], _ = m.headers.fields[0].Update(backspace)
+	m.body.field.Focus()
+	m.body.field.SetCursor(1)
+	m.body.field, _ = m.body.field.Update(backspace)
+
+	if got := c.url.Value(); got != "http://localhost/orders" {
+		t.Errorf("url %q", got)
+	}
+	if got := c.headers.fields[0].Value(); got != "X-Request" {
+		t.Errorf("header %q", got)
+	}
+	if got := c.body.field.Value(); got != "{\"id\": 1}" {
+		t.Errorf("body %q", got)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// theme holds every color of the UI, the names are the ones used in
+// themes.json.
+type theme map[string]string
+
+var themeColors = []string{
+	"text", "grey", "accent", "green", "red", "yellow", "orange", "blue",
+	"primary", "secondary", "focused", "placeholder",
+	"buttonFocused", "buttonFocusedText", "tableBorder", "selected", "selectedText",
+	"activeDot", "inactiveDot",
+}
+
+var builtinThemes = map[string]theme{
+	"dark": {
+		"text": "15", "grey": "237", "accent": "57", "green": "22", "red": "160", "yellow": "3", "orange": "208", "blue": "4",
+		"primary": "236", "secondary": "234", "focused": "238", "placeholder": "241",
+		"buttonFocused": "99", "buttonFocusedText": "13", "tableBorder": "240", "selected": "57", "selectedText": "229",
+		"activeDot": "252", "inactiveDot": "238",
+	},
+	"light": {
+		"text": "235", "grey": "250", "accent": "57", "green": "28", "red": "160", "yellow": "136", "orange": "166", "blue": "25",
+		"primary": "255", "secondary": "254", "focused": "252", "placeholder": "245",
+		"buttonFocused": "99", "buttonFocusedText": "231", "tableBorder": "250", "selected": "57", "selectedText": "231",
+		"activeDot": "235", "inactiveDot": "250",
+	},
+	"high-contrast": {
+		"text": "15", "grey": "244", "accent": "13", "green": "10", "red": "9", "yellow": "11", "orange": "214", "blue": "12",
+		"primary": "0", "secondary": "0", "focused": "19", "placeholder": "250",
+		"buttonFocused": "11", "buttonFocusedText": "0", "tableBorder": "15", "selected": "11", "selectedText": "0",
+		"activeDot": "15", "inactiveDot": "244",
+	},
+}
+
+// themeColor returns the color name of theme t, the "auto" theme picks it
+// from the light or the dark theme depending on the terminal background.
+func themeColor(name, t string, themes map[string]theme) lg.TerminalColor {
+	if t == "auto" {
+		return lg.AdaptiveColor{Light: themes["light"][name], Dark: themes["dark"][name]}
+	}
+	return lg.Color(themes[t][name])
+}
+
+type themeConfig struct {
+	Theme  string
+	Themes map[string]theme
+}
+
+// loadTheme reads themes.json of the config directory, e.g.
+// {"theme": "mine", "themes": {"mine": {"base": "dark", "accent": "#ff79c6"}}}
+// where user themes override the colors of their base (dark by default).
+// Without a config the theme is picked from the terminal background.
+func loadTheme() (string, map[string]theme, error) {
+	themes := map[string]theme{}
+	for name, t := range builtinThemes {
+		themes[name] = t
+	}
+	path, err := configPath("themes.json")
+	if err != nil {
+		return "auto", themes, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "auto", themes, nil
+	}
+	if err != nil {
+		return "auto", themes, err
+	}
+	var config themeConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "auto", themes, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for name, userTheme := range config.Themes {
+		base, ok := builtinThemes[userTheme["base"]]
+		if !ok {
+			base = builtinThemes["dark"]
+		}
+		t := theme{}
+		for _, color := range themeColors {
+			t[color] = base[color]
+			if value, ok := userTheme[color]; ok {
+				t[color] = value
+			}
+		}
+		themes[name] = t
+	}
+	if config.Theme == "" {
+		config.Theme = "auto"
+	}
+	if _, ok := themes[config.Theme]; !ok && config.Theme != "auto" {
+		return "auto", themes, fmt.Errorf("unknown theme %q in %s", config.Theme, path)
+	}
+	return config.Theme, themes, nil
+}
+
+// applyTheme sets the colors and the styles built from them, it has to run
+// before the model is created since components copy their styles.
+func applyTheme(name string, themes map[string]theme) {
+	c := func(color string) lg.TerminalColor { return themeColor(color, name, themes) }
+	grey = c("grey")
+	white = c("text")
+	magenta = c("accent")
+	green = c("green")
+	red = c("red")
+	yellow = c("yellow")
+	orange = c("orange")
+	blue = c("blue")
+	primaryColor = c("primary")
+	secondaryColor = c("secondary")
+	focusedColor = c("focused")
+	placeHolderColor = c("placeholder")
+	buttonFocusedColor = c("buttonFocused")
+	buttonFocusedTextColor = c("buttonFocusedText")
+	tableBorderColor = c("tableBorder")
+	selectedColor = c("selected")
+	selectedTextColor = c("selectedText")
+	activeDotColor = c("activeDot")
+	inactiveDotColor = c("inactiveDot")
+
+	primary = lg.NewStyle().Background(primaryColor)
+	secondary = lg.NewStyle().Background(secondaryColor)
+	focused = lg.NewStyle().Background(focusedColor).Foreground(white)
+
+	nameListStyle = primary.Width(15).Height(10).Border(lg.NormalBorder())
+	nameStyle = primary
+	nameFocusedStyle = focused.Inherit(nameStyle)
+
+	methodFocusedStyle = methodStyle.Inherit(focused)
+
+	buttonFocusedStyle = buttonStyle.Background(buttonFocusedColor).Foreground(buttonFocusedTextColor)
+	buttonUnFocusedStyle = buttonStyle.Background(magenta).Foreground(white)
+
+	responseFocusedStyle = responseStyle.Inherit(focused)
+	responseUnFocusedStyle = responseStyle.Inherit(primary)
+
+	tableFocusedStyle = lg.NewStyle().BorderStyle(lg.NormalBorder()).BorderForeground(focusedColor)
+	tableUnFocusedStyle = lg.NewStyle().BorderStyle(lg.NormalBorder()).BorderForeground(primaryColor)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// historyBarWidth is the width of the timing bar of the history entries
+const historyBarWidth = 12
+
+// TimingPhase is a phase of the request, Start is from the request start
+type TimingPhase struct {
+	Name     string
+	Start    time.Duration
+	Duration time.Duration
+}
+
+// Timing is the breakdown of a response time, a reused connection has no
+// DNS, connect nor TLS phase.
+type Timing struct {
+	Phases []TimingPhase
+	Total  time.Duration
+	Reused bool
+}
+
+// timingTrace keeps the times of the httptrace hooks, set by the traceRecorder
+type timingTrace struct {
+	start                     time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	gotConn, firstByte        time.Time
+	reused                    bool
+}
+
+func (t *timingTrace) timing(done time.Time) Timing {
+	timing := Timing{Total: done.Sub(t.start), Reused: t.reused}
+	phase := func(name string, start, stop time.Time) {
+		if start.IsZero() || stop.IsZero() {
+			return
+		}
+		timing.Phases = append(timing.Phases, TimingPhase{name, start.Sub(t.start), stop.Sub(start)})
+	}
+	phase("DNS", t.dnsStart, t.dnsDone)
+	phase("TCP connect", t.connectStart, t.connectDone)
+	phase("TLS handshake", t.tlsStart, t.tlsDone)
+	phase("Time to first byte", t.gotConn, t.firstByte)
+	phase("Content transfer", t.firstByte, done)
+	return timing
+}
+
+// phaseColor is looked up on render, the theme sets the colors at startup
+func phaseColor(name string) lg.TerminalColor {
+	switch name {
+	case "DNS":
+		return yellow
+	case "TCP connect":
+		return orange
+	case "TLS handshake":
+		return magenta
+	case "Time to first byte":
+		return green
+	}
+	return blue
+}
+
+func formatDuration(d time.Duration) string {
+	if d >= time.Millisecond {
+		return d.Round(time.Millisecond / 10).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+// timingBar draws the phases on width cells, each phase gets at least one
+// cell so the short ones stay visible. With only set to one of them it is
+// drawn where it is in the whole bar.
+func timingBar(t Timing, width int, only int) string {
+	if t.Total <= 0 || width <= 0 {
+		return ""
+	}
+	cell := func(d time.Duration) int { return int(int64(d) * int64(width) / int64(t.Total)) }
+	var b strings.Builder
+	used, drawn := 0, 0
+	for i, p := range t.Phases {
+		start := max(cell(p.Start), used)
+		cells := max(cell(p.Start+p.Duration)-start, 1)
+		cells = min(cells, width-start)
+		if cells <= 0 {
+			break
+		}
+		if only < 0 || only == i {
+			b.WriteString(strings.Repeat(" ", start-drawn))
+			b.WriteString(lg.NewStyle().Foreground(phaseColor(p.Name)).Render(strings.Repeat("█", cells)))
+			drawn = start + cells
+		}
+		used = start + cells
+	}
+	return b.String()
+}
+
+// renderWaterfall shows each phase on its own line, placed on the total time
+func renderWaterfall(t Timing, width int) string {
+	if t.Total <= 0 {
+		return ""
+	}
+	const labelWidth, durationWidth = 20, 10
+	barWidth := width - labelWidth - durationWidth - 2
+	faint := lg.NewStyle().Foreground(placeHolderColor)
+	var lines []string
+	for i, p := range t.Phases {
+		label := lg.NewStyle().Width(labelWidth).Render(p.Name)
+		bar := lg.NewStyle().Width(barWidth).Render(timingBar(t, barWidth, i))
+		lines = append(lines, label+" "+bar+" "+lg.NewStyle().Width(durationWidth).Align(lg.Right).Render(formatDuration(p.Duration)))
+	}
+	total := lg.NewStyle().Width(labelWidth).Bold(true).Render("Total")
+	lines = append(lines, total+" "+lg.NewStyle().Width(barWidth).Render(timingBar(t, barWidth, -1))+" "+
+		lg.NewStyle().Width(durationWidth).Align(lg.Right).Bold(true).Render(formatDuration(t.Total)))
+	if t.Reused {
+		lines = append(lines, faint.Render("connection reused, no DNS, connect nor TLS"))
+	}
+	return strings.Join(lines, "\n")
+}
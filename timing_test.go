@@ -0,0 +1,81 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+func TestTimingPhases(t *testing.T) {
+	t0 := time.Now()
+	at := func(ms int) time.Time { return t0.Add(time.Duration(ms) * time.Millisecond) }
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	tests := []struct {
+		name   string
+		trace  timingTrace
+		done   time.Time
+		phases []TimingPhase
+	}{
+		{"https", timingTrace{start: t0, dnsStart: at(1), dnsDone: at(11), connectStart: at(12), connectDone: at(32),
+			tlsStart: at(32), tlsDone: at(72), gotConn: at(73), firstByte: at(173)}, at(200),
+			[]TimingPhase{{"DNS", ms(1), ms(10)}, {"TCP connect", ms(12), ms(20)}, {"TLS handshake", ms(32), ms(40)},
+				{"Time to first byte", ms(73), ms(100)}, {"Content transfer", ms(173), ms(27)}}},
+		{"http to an IP", timingTrace{start: t0, connectStart: at(0), connectDone: at(5), gotConn: at(5), firstByte: at(15)}, at(16),
+			[]TimingPhase{{"TCP connect", 0, ms(5)}, {"Time to first byte", ms(5), ms(10)}, {"Content transfer", ms(15), ms(1)}}},
+		{"reused", timingTrace{start: t0, gotConn: at(1), firstByte: at(51), reused: true}, at(60),
+			[]TimingPhase{{"Time to first byte", ms(1), ms(50)}, {"Content transfer", ms(51), ms(9)}}},
+		// a phase that didn't finish, like a failed handshake, isn't shown
+		{"unfinished", timingTrace{start: t0, dnsStart: at(1), connectStart: at(2), connectDone: at(3), tlsStart: at(3)}, at(10),
+			[]TimingPhase{{"TCP connect", ms(2), ms(1)}}},
+	}
+	for _, tt := range tests {
+		timing := tt.trace.timing(tt.done)
+		if !slices.Equal(timing.Phases, tt.phases) {
+			t.Errorf("%s: phases %v, want %v", tt.name, timing.Phases, tt.phases)
+		}
+		if timing.Total != tt.done.Sub(t0) || timing.Reused != tt.trace.reused {
+			t.Errorf("%s: total %s, reused %t", tt.name, timing.Total, timing.Reused)
+		}
+	}
+}
+
+func TestTimingBar(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	full := Timing{Total: ms(200), Phases: []TimingPhase{{"DNS", 0, ms(10)}, {"TCP connect", ms(10), ms(20)},
+		{"TLS handshake", ms(30), ms(40)}, {"Time to first byte", ms(70), ms(100)}, {"Content transfer", ms(170), ms(30)}}}
+	tests := []struct {
+		name   string
+		timing Timing
+		width  int
+		only   int
+		want   int // cells drawn
+	}{
+		{"full", full, 20, -1, 20},
+		{"one phase", full, 20, 3, 17}, // the spaces before it and its 10 cells
+		{"width smaller than the phases", full, 3, -1, 3},
+		{"one phase past the width", full, 3, 4, 0},
+		{"width of one", full, 1, -1, 1},
+		{"no width", full, 0, -1, 0},
+		{"negative width", full, -4, -1, 0},
+		{"zero total", Timing{Phases: full.Phases}, 20, -1, 0},
+		{"no phases", Timing{Total: ms(10)}, 20, -1, 0},
+	}
+	for _, tt := range tests {
+		bar := timingBar(tt.timing, tt.width, tt.only)
+		if got := lg.Width(bar); got != tt.want {
+			t.Errorf("%s: %d cells, want %d: %q", tt.name, got, tt.want, bar)
+		}
+	}
+
+	// every phase is visible, even the ones shorter than a cell
+	short := Timing{Total: ms(1000), Phases: []TimingPhase{{"DNS", 0, time.Microsecond}, {"TCP connect", time.Microsecond, time.Microsecond},
+		{"Time to first byte", 2 * time.Microsecond, ms(999)}}}
+	for i := range short.Phases {
+		if bar := timingBar(short, 10, i); !strings.Contains(bar, "█") {
+			t.Errorf("phase %d isn't drawn: %q", i, bar)
+		}
+	}
+}
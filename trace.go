@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// traceEvent is a hook call or a note, at is the monotonic time since the
+// recorder was created.
+type traceEvent struct {
+	at     time.Duration
+	name   string
+	detail string
+	note   bool // free-form text, like the strings signed by the auth
+}
+
+// traceRecorder collects the httptrace events of a request. The hooks can be
+// called from several goroutines (the dialer tries IPv4 and IPv6 in
+// parallel), so everything goes through the lock. One recorder per request,
+// any number of requests can be traced at once.
+type traceRecorder struct {
+	mu     sync.Mutex
+	t0     time.Time
+	events []traceEvent
+	times  timingTrace
+	// the connection attempts by address, the dialer races them
+	attempts map[string]time.Time
+}
+
+func newTraceRecorder() *traceRecorder {
+	return &traceRecorder{t0: time.Now(), attempts: map[string]time.Time{}}
+}
+
+// event records a hook call, the time is taken under the lock so the events
+// are in the order of their times.
+func (r *traceRecorder) event(name, format string, args ...any) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.events = append(r.events, traceEvent{at: now.Sub(r.t0), name: name, detail: fmt.Sprintf(format, args...)})
+	return now
+}
+
+func (r *traceRecorder) notef(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.events = append(r.events, traceEvent{at: now.Sub(r.t0), detail: fmt.Sprintf(format, args...), note: true})
+}
+
+// record sets a time of the timing under the lock, only once the request is
+// being sent so the OAuth token requests don't count.
+func (r *traceRecorder) record(set func(t *timingTrace)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.times.start.IsZero() {
+		set(&r.times)
+	}
+}
+
+// begin marks the request as being sent, the timing starts there
+func (r *traceRecorder) begin() time.Time {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.times = timingTrace{start: now}
+	clear(r.attempts)
+	return now
+}
+
+func (r *traceRecorder) timing(done time.Time) Timing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.times.timing(done)
+}
+
+// logs renders the events in the order they happened
+func (r *traceRecorder) logs() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	for _, e := range r.events {
+		if e.note {
+			b.WriteString(e.detail + "\n\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%8s %s(%s)\n", formatDuration(e.at), e.name, e.detail)
+	}
+	return b.String()
+}
+
+func (r *traceRecorder) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			r.event("GetConn", "%s", hostPort)
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			now := r.event("DNSStart", "%s", info.Host)
+			r.record(func(t *timingTrace) { t.dnsStart = now })
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			now := r.event("DNSDone", "%v, %v", info.Addrs, info.Err)
+			r.record(func(t *timingTrace) { t.dnsDone = now })
+		},
+		ConnectStart: func(network, addr string) {
+			now := r.event("ConnectStart", "%s, %s", network, addr)
+			r.record(func(*timingTrace) { r.attempts[network+" "+addr] = now })
+		},
+		ConnectDone: func(network, addr string, err error) {
+			now := r.event("ConnectDone", "%s, %s, %v", network, addr, err)
+			// the first attempt that connects is the one used
+			r.record(func(t *timingTrace) {
+				if err == nil && t.connectDone.IsZero() {
+					t.connectStart, t.connectDone = r.attempts[network+" "+addr], now
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			now := r.event("TLSHandshakeStart", "")
+			// the transport reports the handshake of HTTP/2 connections
+			// again after wireTransport did it, the first one counts
+			r.record(func(t *timingTrace) {
+				if t.tlsStart.IsZero() {
+					t.tlsStart = now
+				}
+			})
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			now := r.event("TLSHandshakeDone", "%s, %s, %v", tls.VersionName(state.Version), state.NegotiatedProtocol, err)
+			r.record(func(t *timingTrace) {
+				if t.tlsDone.IsZero() {
+					t.tlsDone = now
+				}
+			})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			now := r.event("GotConn", "%v, reused: %t, idle: %s", info.Conn.RemoteAddr(), info.Reused, info.IdleTime)
+			r.record(func(t *timingTrace) { t.gotConn, t.reused = now, info.Reused })
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			r.event("WroteRequest", "%v", info.Err)
+		},
+		GotFirstResponseByte: func() {
+			now := r.event("GotFirstResponseByte", "")
+			r.record(func(t *timingTrace) { t.firstByte = now })
+		},
+		PutIdleConn: func(err error) {
+			r.event("PutIdleConn", "%v", err)
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// the hooks are called at once like the dialer racing IPv4 and IPv6
+func TestTraceRecorderConcurrentHooks(t *testing.T) {
+	r := newTraceRecorder()
+	r.begin()
+	trace := r.clientTrace()
+	const attempts = 16
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addr := fmt.Sprintf("10.0.0.%d:443", i)
+			trace.DNSStart(httptrace.DNSStartInfo{Host: "example.com"})
+			trace.ConnectStart("tcp", addr)
+			var err error
+			if i%2 == 1 {
+				err = errors.New("refused")
+			}
+			trace.ConnectDone("tcp", addr, err)
+			trace.TLSHandshakeStart()
+			trace.TLSHandshakeDone(tls.ConnectionState{}, nil)
+			r.notef("attempt %d", i)
+		}()
+	}
+	wg.Wait()
+
+	if len(r.events) != attempts*6 {
+		t.Fatalf("%d events, want %d", len(r.events), attempts*6)
+	}
+	count := map[string]int{}
+	for i, e := range r.events {
+		count[e.name]++
+		if i > 0 && e.at < r.events[i-1].at {
+			t.Errorf("event %d %s at %s is before the previous one at %s", i, e.name, e.at, r.events[i-1].at)
+		}
+	}
+	for _, name := range []string{"DNSStart", "ConnectStart", "ConnectDone", "TLSHandshakeStart", "TLSHandshakeDone", ""} {
+		if count[name] != attempts {
+			t.Errorf("%d %q events, want %d", count[name], name, attempts)
+		}
+	}
+	// the connect phase is the one of the first attempt that connected
+	times := r.times
+	if times.connectStart.IsZero() || times.connectDone.Before(times.connectStart) {
+		t.Errorf("connect from %v to %v", times.connectStart, times.connectDone)
+	}
+	if times.tlsStart.IsZero() || times.tlsDone.IsZero() {
+		t.Error("the TLS handshake wasn't timed")
+	}
+}
+
+// requests traced at once through a transport, each in its own recorder
+func TestTraceRecorderTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	defer transport.CloseIdleConnections()
+
+	const requests = 8
+	recorders := make([]*traceRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		recorders[i] = newTraceRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), recorders[i].clientTrace()))
+			recorders[i].begin()
+			res, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	want := []string{"GetConn", "ConnectStart", "ConnectDone", "TLSHandshakeStart", "TLSHandshakeDone", "GotConn", "WroteRequest", "GotFirstResponseByte"}
+	for i, r := range recorders {
+		var names []string
+		for _, e := range r.events {
+			names = append(names, e.name)
+		}
+		if !slices.Equal(names, want) {
+			t.Errorf("request %d: events %v, want %v", i, names, want)
+		}
+		timing := r.times.timing(r.times.firstByte)
+		if len(timing.Phases) != 4 || timing.Reused {
+			t.Errorf("request %d: phases %+v", i, timing.Phases)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
 	"slices"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -30,6 +33,7 @@ const (
 	responseHeadersView
 	responseCookiesView
 	responseLogs
+	responseRawView
 	// non tab-able views
 	methodOptionsView
 	bodyOptionsView
@@ -39,6 +43,10 @@ const (
 	queryContentView
 	headersContentView
 	historyOptionsView
+	paletteView
+	cookiesView
+	historyBrowserView
+	searchView
 )
 
 func (m model) Init() tea.Cmd {
@@ -50,32 +58,134 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case response:
+		m.err = nil
+		if m.jar != nil {
+			m.err = m.jar.saveErr()
+		}
 		res := saveResponse(m, msg)
-		m.requests.items[m.requests.cursor].Responses = append(m.requests.items[m.requests.cursor].Responses, res)
-		// now update the UI
-		m.resBody.SetContent(msg.body)
-		m.resLogs = msg.traceLogs
-		setTableRows(&m.resHeaders, msg.headers)
-		setTableRows(&m.resCookies, msg.cookies)
+		if msg.tokenKey != "" {
+			m.tokens[msg.tokenKey] = msg.token
+		}
+		// newest first, as loaded from the store
+		msg.request.Responses = slices.Insert(msg.request.Responses, 0, res)
+		// now update the UI of the tab that sent it
+		if i := tabIndex(&m, msg.request); i == m.activeTab {
+			applyResponse(&m.tabState, res)
+		} else if i >= 0 {
+			applyResponse(&m.tabs[i].state, res)
+		}
+	case editorFinishedMsg:
+		return editorFinished(m, msg)
+	case pagerFinishedMsg:
+		os.Remove(msg.path)
+		return m, nil
+	case tea.MouseMsg:
+		return updateMouse(m, msg)
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		resize(&m)
+		return m, nil
 	case errMsg:
-		log.Fatal(m.err.Error())
-		return m, tea.Quit
+		// shown in the status line until the next request, the open tabs
+		// may have unsaved changes
+		m.err = msg.err
+		return m, nil
 	case tea.KeyMsg:
-		key := msg.String()
+		if m.confirmClose {
+			m.confirmClose = false
+			switch {
+			case key.Matches(msg, keys.Save):
+				saveRequest(m)
+				closeTab(&m, m.activeTab)
+			case key.Matches(msg, keys.Discard):
+				closeTab(&m, m.activeTab)
+			}
+			return m, nil
+		}
+		if m.confirmQuit {
+			m.confirmQuit = false
+			switch {
+			case key.Matches(msg, keys.Save):
+				saveTabs(&m)
+				return m, tea.Quit
+			case key.Matches(msg, keys.Discard):
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		if view == paletteView {
+			return updatePalette(m, msg)
+		}
+		if view == cookiesView {
+			return updateCookies(m, msg)
+		}
+		if view == historyBrowserView {
+			return updateHistoryBrowser(m, msg)
+		}
+		if view == searchView {
+			return updateSearch(m, msg)
+		}
+		// saving works in insert mode too, the fields are read as they are
+		if key.Matches(msg, keys.Save) {
+			saveRequest(m)
+			return m, nil
+		}
 		switch mode {
 		case normal:
-			switch key {
-			case "n":
+			switch {
+			case key.Matches(msg, keys.Help):
+				m.showHelp = !m.showHelp
+			case key.Matches(msg, keys.Reveal):
+				switch view {
+				case authTypeView, authContentView:
+					m.revealSecrets = !m.revealSecrets
+				}
+			case key.Matches(msg, keys.Revert):
+				if len(m.tabs) > 0 {
+					setUIRequest(&m, m.tabs[m.activeTab].request)
+				}
+			case key.Matches(msg, keys.NextTab, keys.PrevTab):
+				if len(m.tabs) == 0 {
+					break
+				}
+				offset := 1
+				if key.Matches(msg, keys.PrevTab) {
+					offset = len(m.tabs) - 1
+				}
+				switchTab(&m, (m.activeTab+offset)%len(m.tabs))
+			case key.Matches(msg, keys.OpenTab):
+				if view == requestsView && len(m.requests.items) > 0 {
+					openTab(&m, m.requests.items[m.requests.cursor])
+				}
+			case key.Matches(msg, keys.CloseTab):
+				// the last tab stays open, it is what the right panel shows
+				switch {
+				case len(m.tabs) <= 1:
+				case unsaved(&m, m.tabs[m.activeTab].request):
+					m.confirmClose = true
+				default:
+					closeTab(&m, m.activeTab)
+				}
+			case key.Matches(msg, keys.Edit):
+				if len(m.requests.items) > 0 {
+					return m, editField(m)
+				}
+			case key.Matches(msg, keys.Palette):
+				return openPalette(m)
+			case key.Matches(msg, keys.Cookies):
+				return openCookies(m), nil
+			case key.Matches(msg, keys.History):
+				return openHistoryBrowser(m)
+			case key.Matches(msg, keys.Search):
+				return openSearch(m)
+			case key.Matches(msg, keys.New):
 				switch view {
 				case requestsView:
 					tempName := makeInputField("New Request", "")
 					m.names = append(m.names, tempName)
 					m.requests.cursor = len(m.names) - 1
-					req := &DBRequest{}
-					for _, option := range m.body.options {
-						req.Body.Types = append(req.Body.Types, &NameValue{option.type_, ""})
-					}
-					m.requests.items = append(m.requests.items, req)
+					m.requests.items = append(m.requests.items, newRequest(m))
+					openTab(&m, m.requests.items[m.requests.cursor])
 					m.names[m.requests.cursor].Focus()
 					mode = insert
 				case queryView:
@@ -93,29 +203,148 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					view = headersContentView
 					mode = insert
 				}
-			case "d":
+			case key.Matches(msg, keys.Delete):
 				switch view {
 				case requestsView:
 					if len(m.names) == 0 {
 						break
 					}
+					deleted := m.requests.items[m.requests.cursor]
 					var err error
-					if err = m.db.DeleteRequest(m.requests.items[m.requests.cursor]); err != nil {
+					if err = m.db.DeleteRequest(deleted); err != nil {
 						// TODO: handle error instead of quitting
 						return m, tea.Quit
 					}
+					m.trash = append(m.trash, trashedRequest{deleted, m.requests.cursor})
 					m.names = slices.Delete(m.names, m.requests.cursor, m.requests.cursor+1)
 					m.requests.items = slices.Delete(m.requests.items, m.requests.cursor, m.requests.cursor+1)
 					m.requests.cursor = min(m.requests.cursor, len(m.names)-1)
 					m.requests.cursor = max(m.requests.cursor, 0)
-					if len(m.requests.items) > 0 {
-						setUIRequest(&m, m.requests.items[m.requests.cursor])
-					} else {
+					if i := tabIndex(&m, deleted); i >= 0 {
+						closeTab(&m, i)
+					}
+					if len(m.requests.items) > 0 && len(m.tabs) == 0 {
+						openTab(&m, m.requests.items[m.requests.cursor])
+					} else if len(m.requests.items) == 0 {
 						m.method.cursor = 0
 						m.url.SetValue("")
 					}
+				case historyOptionsView:
+					res := selectedResponse(&m)
+					if res == nil {
+						break
+					}
+					r := m.requests.items[m.requests.cursor]
+					if err := m.db.DeleteResponse(res.ID); err != nil {
+						m.err = fmt.Errorf("failed to delete response: %w", err)
+						break
+					}
+					r.Responses = slices.Delete(r.Responses, m.history.cursor, m.history.cursor+1)
+					showHistoryEntry(&m)
+				}
+			case key.Matches(msg, keys.Pin):
+				if res := selectedResponse(&m); view == historyOptionsView && res != nil {
+					if err := m.db.PinResponse(res.ID, !res.Pinned); err != nil {
+						m.err = fmt.Errorf("failed to pin response: %w", err)
+						break
+					}
+					res.Pinned = !res.Pinned
+				}
+			case key.Matches(msg, keys.Prune):
+				pruneHistory(&m)
+			case key.Matches(msg, keys.MoveDown, keys.MoveUp):
+				switch view {
+				case requestsView:
+					offset := 1
+					if key.Matches(msg, keys.MoveUp) {
+						offset = -1
+					}
+					cursor := m.requests.cursor
+					if !m.requests.swap(offset) {
+						break
+					}
+					m.names[cursor], m.names[m.requests.cursor] = m.names[m.requests.cursor], m.names[cursor]
+					// moving a request by hand means the displayed order becomes the custom one
+					if m.sortMode != sortPosition {
+						m.sortMode = sortPosition
+						if err := m.db.SetSetting("sort", sortModes[m.sortMode]); err != nil {
+							m.err = fmt.Errorf("failed to save sort mode: %w", err)
+						}
+					}
+					if err := m.db.SetPositions(m.requests.items); err != nil {
+						m.err = fmt.Errorf("failed to save request positions: %w", err)
+					}
+				}
+			case key.Matches(msg, keys.Sort):
+				switch view {
+				case requestsView:
+					m.sortMode = (m.sortMode + 1) % len(sortModes)
+					if err := m.db.SetSetting("sort", sortModes[m.sortMode]); err != nil {
+						m.err = fmt.Errorf("failed to save sort mode: %w", err)
+					}
+					sortRequests(&m)
+				}
+			case key.Matches(msg, keys.GrowRequest, keys.ShrinkRequest):
+				// move the split between the request and the response pane
+				if key.Matches(msg, keys.GrowRequest) {
+					m.requestHeight++
+				} else {
+					m.requestHeight--
+				}
+				resize(&m)
+			case key.Matches(msg, keys.Copy):
+				switch view {
+				case requestsView:
+					if len(m.requests.items) == 0 {
+						break
+					}
+					req := m.requests.items[m.requests.cursor]
+					addRequest(&m, req.Copy(req.Name+" copy"))
+				case historyOptionsView:
+					if res := selectedResponse(&m); res != nil {
+						addRequest(&m, requestFromResponse(m, *res))
+						view = requestsView
+					}
+				}
+			case key.Matches(msg, keys.Restore):
+				switch view {
+				case historyOptionsView:
+					// only the tab changes, it is saved like any other edit
+					res := selectedResponse(&m)
+					if res == nil {
+						break
+					}
+					restored := *res
+					if restored.Request == nil {
+						restored.Request = legacySnapshot(m, restored)
+					}
+					restoreRequest(&m, restored)
+					view = historyView
+				}
+			case key.Matches(msg, keys.Inspect):
+				if view == historyOptionsView {
+					m.showSent = !m.showSent
+				}
+			case key.Matches(msg, keys.Undo):
+				switch view {
+				case requestsView:
+					if len(m.trash) == 0 {
+						break
+					}
+					trashed := m.trash[len(m.trash)-1]
+					// the request stays in the trash to be tried again
+					if err := m.db.RestoreRequest(trashed.request); err != nil {
+						m.err = fmt.Errorf("failed to restore request: %w", err)
+						break
+					}
+					m.trash = m.trash[:len(m.trash)-1]
+					index := min(trashed.index, len(m.requests.items))
+					m.names = slices.Insert(m.names, index, makeInputField(trashed.request.Name, ""))
+					m.requests.items = slices.Insert(m.requests.items, index, trashed.request)
+					m.requests.cursor = index
+					showRequest(&m, trashed.request)
 				}
-			case "enter":
+			case key.Matches(msg, keys.Select):
 				switch view {
 				case requestsView:
 					cmd = m.names[m.requests.cursor].Focus()
@@ -124,26 +353,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case httpMethodView:
 					view = methodOptionsView
 				case methodOptionsView:
-					m.requests.items[m.requests.cursor].Method = m.method.options[m.method.cursor]
-					saveRequest(m)
 					view = httpMethodView
 				case urlView:
 					cmd = m.url.Focus()
 					mode = insert
 					return m, cmd
 				case sendView:
+					m.err = nil
 					return m, sendRequest(m)
 				case bodyTypeView:
 					view = bodyOptionsView
 				case bodyOptionsView:
 					m.body.field.SetValue(m.body.options[m.body.cursor].value)
-					saveRequest(m)
 					view = bodyTypeView
 				case authTypeView:
 					view = authOptionsView
 				case authOptionsView:
-					m.requests.items[m.requests.cursor].Auth.Selected = m.auth.cursor
-					saveRequest(m)
 					view = authTypeView
 				case queryContentView:
 					cmd = m.queryParams.fields[m.queryParams.cursor].Focus()
@@ -167,7 +392,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					cmd = m.body.field.Focus()
 					mode = insert
 					return m, cmd
-				case responseView:
+				case responseView, responseRawView:
 					mode = insert // lame way to "focus" the response viewport
 				case responseHeadersView:
 					m.resHeaders.Focus()
@@ -176,18 +401,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.resCookies.Focus()
 					mode = insert
 				case historyView:
-					view = historyOptionsView
+					// a request never sent has no history to choose from
+					if selectedResponse(&m) != nil {
+						view = historyOptionsView
+					}
 				case historyOptionsView:
-					m.resBody.SetContent(m.requests.items[m.requests.cursor].Responses[m.history.cursor].Body)
-					setTableRows(&m.resHeaders, m.requests.items[m.requests.cursor].Responses[m.history.cursor].Headers)
-					setTableRows(&m.resCookies, m.requests.items[m.requests.cursor].Responses[m.history.cursor].Cookies)
+					if res := selectedResponse(&m); res != nil {
+						m.resBody.SetContent(res.Body)
+						setTableRows(&m.resHeaders, res.Headers)
+						setCookieRows(&m.resCookies, res.Cookies)
+						m.resLogs = res.TraceLogs
+						m.resTiming = res.Timing
+						setRawContent(&m.resRaw, res.Raw)
+					}
 					view = historyView
 				}
-			case "j":
+			case key.Matches(msg, keys.Down):
 				switch view {
 				case requestsView:
 					m.requests.down()
-					setUIRequest(&m, m.requests.items[m.requests.cursor])
+					showRequest(&m, m.requests.items[m.requests.cursor])
 				case httpMethodView:
 					view = bodyTypeView + m.requestPaginator.Page
 				case methodOptionsView:
@@ -234,11 +467,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case historyOptionsView:
 					m.history.cursor = min(m.history.cursor+1, len(m.requests.items[m.requests.cursor].Responses)-1)
 				}
-			case "k":
+			case key.Matches(msg, keys.Up):
 				switch view {
 				case requestsView:
 					m.requests.up()
-					setUIRequest(&m, m.requests.items[m.requests.cursor])
+					showRequest(&m, m.requests.items[m.requests.cursor])
 				case methodOptionsView:
 					m.method.up()
 				case bodyTypeView:
@@ -287,10 +520,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				case historyOptionsView:
 					m.history.cursor = max(m.history.cursor-1, 0)
-				case responseView, responseHeadersView, responseCookiesView, responseLogs:
+				case responseView, responseHeadersView, responseCookiesView, responseLogs, responseRawView:
 					view = historyView
 				}
-			case "tab", "l":
+			case key.Matches(msg, keys.Next):
 				switch view {
 				case requestsView, httpMethodView, urlView:
 					view++
@@ -301,11 +534,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.queryParams.cursor = min(m.queryParams.cursor+1, len(m.queryParams.fields)-1)
 				case headersContentView:
 					m.headers.cursor = min(m.headers.cursor+1, len(m.headers.fields)-1)
-				case responseView, responseHeadersView, responseCookiesView:
+				case responseView, responseHeadersView, responseCookiesView, responseLogs:
 					m.responsePaginator.NextPage()
 					view++
 				}
-			case "shift+tab", "h":
+			case key.Matches(msg, keys.Prev):
 				switch view {
 				case httpMethodView, urlView, sendView:
 					view--
@@ -318,11 +551,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.queryParams.cursor = max(m.queryParams.cursor-1, 0)
 				case headersContentView:
 					m.headers.cursor = max(m.headers.cursor-1, 0)
-				case responseHeadersView, responseCookiesView, responseLogs:
+				case responseHeadersView, responseCookiesView, responseLogs, responseRawView:
 					m.responsePaginator.PrevPage()
 					view--
 				}
-			case "esc":
+			case key.Matches(msg, keys.Back):
+				m.showHelp = false
 				switch view {
 				case methodOptionsView:
 					view = httpMethodView
@@ -343,80 +577,62 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case responseCookiesView:
 					m.resCookies.Blur()
 				}
-			case "q":
+			case key.Matches(msg, keys.Quit):
+				if unsavedTabs(&m) > 0 {
+					m.confirmQuit = true
+					return m, nil
+				}
 				return m, tea.Quit
 			}
 		case insert:
 			switch view {
 			case requestsView:
-				switch key {
-				case "esc":
-					m.requests.items[m.requests.cursor].Name = m.names[m.requests.cursor].Value()
+				switch {
+				case key.Matches(msg, keys.Done):
+					// renaming saves the name only, the other edits stay pending
+					req := m.requests.items[m.requests.cursor]
+					req.Name = m.names[m.requests.cursor].Value()
 					m.names[m.requests.cursor].Blur()
 					mode = normal
-					saveRequest(m)
+					if err := m.db.SaveRequest(req); err != nil {
+						log.Fatal("Error saving request: ", err)
+					}
 				}
 			case urlView:
-				switch key {
-				case "esc":
-					m.requests.items[m.requests.cursor].Url = m.url.Value()
+				switch {
+				case key.Matches(msg, keys.Done):
 					m.url.Blur()
 					mode = normal
-					saveRequest(m)
 				}
 			case bodyContentView:
-				switch key {
-				case "esc":
+				switch {
+				case key.Matches(msg, keys.Done):
 					m.body.options[m.body.cursor].value = m.body.field.Value()
 					m.body.field.Blur()
-					saveRequest(m)
 					mode = normal
 				}
 			case queryContentView:
-				switch key {
-				case "esc":
-					m.requests.items[m.requests.cursor].Query = nil
-					for i := range len(m.queryParams.fields) / 2 {
-						name := m.queryParams.fields[i*2].Value()
-						value := m.queryParams.fields[i*2+1].Value()
-						if len(name) == 0 && len(value) == 0 {
-							continue
-						}
-						m.requests.items[m.requests.cursor].Query = append(m.requests.items[m.requests.cursor].Query, NameValue{name, value})
-					}
+				switch {
+				case key.Matches(msg, keys.Done):
 					m.queryParams.fields[m.queryParams.cursor].Blur()
-					saveRequest(m)
 					mode = normal
 				}
 			case headersContentView:
-				switch key {
-				case "esc":
-					m.requests.items[m.requests.cursor].Headers = nil
-					for i := range len(m.headers.fields) / 2 {
-						name := m.headers.fields[i*2].Value()
-						value := m.headers.fields[i*2+1].Value()
-						if len(name) == 0 && len(value) == 0 {
-							continue
-						}
-						m.requests.items[m.requests.cursor].Headers = append(m.requests.items[m.requests.cursor].Headers, NameValue{name, value})
-					}
+				switch {
+				case key.Matches(msg, keys.Done):
 					m.headers.fields[m.headers.cursor].Blur()
-					saveRequest(m)
 					mode = normal
 				}
 			case authContentView:
 				authType := m.auth.options[m.auth.cursor]
-				switch key {
-				case "esc":
-					value := authType.fields[authType.cursor].Value()
-					m.requests.items[m.requests.cursor].Auth.Types[m.auth.cursor].Fields[authType.cursor].Value = value
+				switch {
+				case key.Matches(msg, keys.Done):
 					authType.fields[authType.cursor].Blur()
-					saveRequest(m)
 					mode = normal
 				}
-			case responseView, responseHeadersView, responseCookiesView:
-				switch key {
-				case "esc":
+			case responseView, responseHeadersView, responseCookiesView, responseRawView:
+				switch {
+				case key.Matches(msg, keys.Done):
 					mode = normal // again lame way to "unfocus" the response viewport
 				}
 			}
@@ -461,6 +677,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if mode == insert {
 			m.resBody, cmd = m.resBody.Update(msg)
 		}
+	case responseRawView:
+		if mode == insert {
+			m.resRaw, cmd = m.resRaw.Update(msg)
+		}
 	case responseHeadersView:
 		if m.resHeaders.Focused() {
 			m.resHeaders, cmd = m.resHeaders.Update(msg)
@@ -473,26 +693,65 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// newRequest returns an empty request with all the body types of the UI
+func newRequest(m model) *DBRequest {
+	req := &DBRequest{}
+	for _, option := range m.body.options {
+		req.Body.Types = append(req.Body.Types, &NameValue{option.type_, ""})
+	}
+	for i, option := range m.auth.options {
+		req.Auth.Types = append(req.Auth.Types, dbAuthType{option.name, []*NameValue{}})
+		for _, field := range option.fields {
+			req.Auth.Types[i].Fields = append(req.Auth.Types[i].Fields, &NameValue{field.Placeholder, ""})
+		}
+	}
+	return req
+}
+
+// addRequest saves the request and selects it right below the current one
+func addRequest(m *model, req *DBRequest) {
+	if err := m.db.SaveRequest(req); err != nil {
+		log.Fatal("Error saving request: ", err)
+	}
+	index := min(m.requests.cursor+1, len(m.requests.items))
+	m.names = slices.Insert(m.names, index, makeInputField(req.Name, ""))
+	m.requests.items = slices.Insert(m.requests.items, index, req)
+	m.requests.cursor = index
+	if m.sortMode == sortPosition {
+		if err := m.db.SetPositions(m.requests.items); err != nil {
+			log.Fatal("Error saving request positions: ", err)
+		}
+	} else {
+		sortRequests(m)
+	}
+	openTab(m, req)
+}
+
+// saveRequest stores the state of the active tab in its request
 func saveRequest(m model) {
-	req := m.requests.items[m.requests.cursor]
-	if req == nil && req.Name == "" {
+	if len(m.tabs) == 0 {
 		return
 	}
+	req := m.tabs[m.activeTab].request
 	req.Method = m.method.options[m.method.cursor]
 	req.Url = m.url.Value()
 
+	// the field may be in insert mode, its option is only set on esc
+	if m.body.cursor != 0 {
+		m.body.options[m.body.cursor].value = m.body.field.Value()
+	}
 	for i, option := range m.body.options {
 		req.Body.Types[i].Value = option.value
 	}
 	req.Body.Selected = m.body.cursor
 
-	// Auth
-	if len(req.Auth.Types) == 0 {
-		for i, option := range m.auth.options {
+	// Auth, requests saved before an auth type or field existed get it now
+	for i, option := range m.auth.options {
+		if i == len(req.Auth.Types) {
 			req.Auth.Types = append(req.Auth.Types, dbAuthType{option.name, []*NameValue{}})
-			for _, field := range option.fields {
-				req.Auth.Types[i].Fields = append(req.Auth.Types[i].Fields, &NameValue{field.Placeholder, ""})
-			}
+		}
+		for j := len(req.Auth.Types[i].Fields); j < len(option.fields); j++ {
+			req.Auth.Types[i].Fields = append(req.Auth.Types[i].Fields, &NameValue{option.fields[j].Placeholder, ""})
 		}
 	}
 	for i, type_ := range m.auth.options {
@@ -503,6 +762,9 @@ func saveRequest(m model) {
 	}
 	req.Auth.Selected = m.auth.cursor
 
+	req.Query = nameValues(m.queryParams.fields)
+	req.Headers = nameValues(m.headers.fields)
+
 	var err error
 	if err = m.db.SaveRequest(req); err != nil {
 		log.Fatal("Error saving request: ", err)
@@ -512,9 +774,9 @@ func saveRequest(m model) {
 func saveResponse(m model, msg response) DBResponse {
 	response := DBResponse{
 		0,
-		m.requests.items[m.requests.cursor].ID,
-		m.requests.items[m.requests.cursor].Method,
-		m.requests.items[m.requests.cursor].Url,
+		msg.request.ID,
+		msg.method,
+		msg.url,
 		msg.body,
 		msg.status,
 		msg.headers,
@@ -523,6 +785,10 @@ func saveResponse(m model, msg response) DBResponse {
 		msg.contentLength,
 		msg.responseAt,
 		msg.traceLogs,
+		msg.timing,
+		msg.raw,
+		msg.sent,
+		false,
 	}
 	var err error
 	if err = m.db.SaveResponse(&response); err != nil {
@@ -1,58 +1,97 @@
 package main
 
 import (
+	"cmp"
+	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
 	lg "github.com/charmbracelet/lipgloss"
 	"github.com/dustin/go-humanize"
 )
 
 var (
-	rightPanelWidth  = 80
-	grey             = lg.Color("237")
-	white            = lg.Color("15")
-	magenta          = lg.Color("57")
-	green            = lg.Color("22")
-	red              = lg.Color("160")
-	yellow           = lg.Color("3")
-	orange           = lg.Color("208")
-	blue             = lg.Color("4")
-	primaryColor     = lg.Color("236")
-	secondaryColor   = lg.Color("234")
-	focusedColor     = lg.Color("238")
-	primary          = lg.NewStyle().Background(primaryColor)
-	secondary        = lg.NewStyle().Background(secondaryColor)
-	focused          = lg.NewStyle().Background(focusedColor).Foreground(white)
-	placeHolderColor = lg.Color("241")
-
-	nameListStyle    = primary.Width(15).Height(10).Border(lg.NormalBorder())
-	nameStyle        = primary
-	nameFocusedStyle = focused.Inherit(nameStyle)
+	rightPanelWidth = 80
+
+	// colors and the styles using them are set by applyTheme
+	grey, white, magenta, green, red, yellow, orange, blue       lg.TerminalColor
+	primaryColor, secondaryColor, focusedColor, placeHolderColor lg.TerminalColor
+	buttonFocusedColor, buttonFocusedTextColor                   lg.TerminalColor
+	tableBorderColor, selectedColor, selectedTextColor           lg.TerminalColor
+	activeDotColor, inactiveDotColor                             lg.TerminalColor
+
+	primary, secondary, focused lg.Style
+
+	nameListStyle, nameStyle, nameFocusedStyle lg.Style
 
 	methodStyle        = lg.NewStyle().Width(9).AlignHorizontal(lg.Center).Padding(0, 1, 0)
-	methodFocusedStyle = methodStyle.Inherit(focused)
+	methodFocusedStyle lg.Style
 
 	urlWidth = rightPanelWidth - methodStyle.GetWidth() - buttonStyle.GetWidth() - 3
 
-	buttonStyle          = lg.NewStyle().Padding(0, 1, 0).Width(6)
-	buttonFocusedStyle   = buttonStyle.Background(lg.Color("99")).Foreground(lg.Color("13"))
-	buttonUnFocusedStyle = buttonStyle.Background(magenta).Foreground(white)
+	buttonStyle                              = lg.NewStyle().Padding(0, 1, 0).Width(6)
+	buttonFocusedStyle, buttonUnFocusedStyle lg.Style
 
-	responseStyle          = lg.NewStyle().Width(rightPanelWidth)
-	responseFocusedStyle   = responseStyle.Inherit(focused)
-	responseUnFocusedStyle = responseStyle.Inherit(primary)
+	responseStyle                                = lg.NewStyle().Width(rightPanelWidth)
+	responseFocusedStyle, responseUnFocusedStyle lg.Style
 
 	requestPaginatorStyle = lg.NewStyle().Width(rightPanelWidth).Height(10).Border(lg.NormalBorder())
 
-	tableFocusedStyle   = lg.NewStyle().BorderStyle(lg.NormalBorder()).BorderForeground(focusedColor)
-	tableUnFocusedStyle = lg.NewStyle().BorderStyle(lg.NormalBorder()).BorderForeground(primaryColor)
+	tableFocusedStyle, tableUnFocusedStyle lg.Style
 
 	down_arrow = lg.NewStyle().PaddingRight(1).Render("")
+
+	helpKeys = renderHelpKeys()
 )
 
+const (
+	minRightPanelWidth = 40
+	minRequestHeight   = 6
+	minResponseHeight  = 5
+	// tab bar, request url, request settings, status line and response dots
+	fixedHeight = 1 + 3 + 3 + 3 + 2
+	// help keys and sort mode above the sidebar
+	sidebarHeaderHeight = 2
+)
+
+// resize recomputes the panel sizes from the terminal size and the height
+// given to the request pane, the response pane takes the rest.
+func resize(m *model) {
+	sidebarWidth := max(lg.Width(helpKeys), nameListStyle.GetWidth()+2)
+	rightPanelWidth = max(m.width-sidebarWidth-2, minRightPanelWidth)
+	urlWidth = rightPanelWidth - methodStyle.GetWidth() - buttonStyle.GetWidth() - 3
+
+	maxRequestHeight := max(m.height-fixedHeight-minResponseHeight-2, minRequestHeight)
+	m.requestHeight = min(max(m.requestHeight, minRequestHeight), maxRequestHeight)
+	responseHeight := max(m.height-fixedHeight-m.requestHeight-2, minResponseHeight)
+
+	nameListStyle = nameListStyle.Height(max(m.height-sidebarHeaderHeight-2, 1))
+	requestPaginatorStyle = requestPaginatorStyle.Width(rightPanelWidth).Height(m.requestHeight)
+	responseStyle = responseStyle.Width(rightPanelWidth)
+	responseFocusedStyle = responseStyle.Inherit(focused)
+	responseUnFocusedStyle = responseStyle.Inherit(primary)
+
+	m.body.field.SetWidth(rightPanelWidth - 2)
+	m.body.field.SetHeight(m.requestHeight - 4)
+	m.resBody.Width = rightPanelWidth
+	m.resBody.Height = responseHeight
+	m.resRaw.Width = rightPanelWidth
+	m.resRaw.Height = responseHeight
+	// 2 for the table border and 2 for the padding of each cell
+	columnWidth := (rightPanelWidth-2)/2 - 2
+	m.resHeaders.SetColumns([]table.Column{{Title: "Name", Width: columnWidth}, {Title: "Value", Width: columnWidth}})
+	m.resHeaders.SetWidth(rightPanelWidth)
+	m.resHeaders.SetHeight(responseHeight - 4)
+	m.resCookies.SetColumns(cookieColumns(rightPanelWidth))
+	m.resCookies.SetWidth(rightPanelWidth)
+	// the line under the table shows the warnings
+	m.resCookies.SetHeight(responseHeight - 5)
+}
+
 func coloredMethod(s string) string {
-	var color lg.Color
+	var color lg.TerminalColor
 	switch s {
 	case "GET":
 		color = magenta
@@ -71,7 +110,7 @@ func coloredMethod(s string) string {
 }
 
 func getStatusStyle(s string) lg.Style {
-	var color lg.Color
+	var color lg.TerminalColor
 	switch string(s[0]) {
 	case "2":
 		color = green
@@ -88,18 +127,66 @@ func getStatusStyle(s string) lg.Style {
 func renderRequestNames(m model) string {
 	pipe := primary.Foreground(magenta).Render("| ")
 	var names []string
+	// scroll so the selected request is always visible
+	offset := max(0, m.requests.cursor-nameListStyle.GetHeight()+1)
 	for i, field := range m.names {
+		if i < offset || i >= offset+nameListStyle.GetHeight() {
+			continue
+		}
+		marker := ""
+		if unsaved(&m, m.requests.items[i]) {
+			marker = primary.Foreground(orange).Render(" ●")
+		}
 		if view == requestsView && i == m.requests.cursor {
-			names = append(names, pipe+nameFocusedStyle.Render(field.View()))
+			names = append(names, pipe+nameFocusedStyle.Render(field.View())+marker)
 		} else if i == m.requests.cursor {
-			names = append(names, pipe+nameStyle.Render(field.View()))
+			names = append(names, pipe+nameStyle.Render(field.View())+marker)
 		} else {
-			names = append(names, primary.Render("  ")+nameStyle.Render(field.View()))
+			names = append(names, primary.Render("  ")+nameStyle.Render(field.View())+marker)
 		}
 	}
 	return nameListStyle.Render(strings.Join(names, "\n"))
 }
 
+func renderHelpKeys() string {
+	actions := []struct {
+		icon    string
+		binding key.Binding
+	}{
+		{"\uf055", keys.New},
+		{"\U000f01b4", keys.Delete},
+		{"\U000f018f", keys.Copy},
+		{"\U000f054c", keys.Undo},
+		{"\U000f02d7", keys.Help},
+	}
+	var b strings.Builder
+	for _, action := range actions {
+		b.WriteString(" " + action.icon + " (" + action.binding.Help().Key + ")")
+	}
+	return b.String() + "\n"
+}
+
+func renderSortMode(m model) string {
+	return lg.NewStyle().Foreground(placeHolderColor).Render(" 󰒺 " + sortModes[m.sortMode] + " (" + keys.Sort.Help().Key + ")")
+}
+
+func renderHelp(m model) string {
+	title := lg.NewStyle().Bold(true).Render("Key bindings")
+	m.help.ShowAll = true
+	return secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(title + "\n\n" + m.help.View(helpFor(view)))
+}
+
+func renderUnsavedPrompt(m model) string {
+	title := lg.NewStyle().Bold(true).Render("Unsaved changes")
+	text := fmt.Sprintf("%d open tab(s) have unsaved changes.\n\n%s save all and quit\n%s quit without saving\nany other key to cancel",
+		unsavedTabs(&m), keys.Save.Help().Key, keys.Discard.Help().Key)
+	if m.confirmClose {
+		text = fmt.Sprintf("The tab has unsaved changes.\n\n%s save and close\n%s close without saving\nany other key to cancel",
+			keys.Save.Help().Key, keys.Discard.Help().Key)
+	}
+	return secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(title + "\n\n" + text)
+}
+
 func renderMethod(f selectField) string {
 	switch view {
 	case httpMethodView:
@@ -229,6 +316,16 @@ func renderRequestBody(m model) string {
 	return m.body.field.View()
 }
 
+// authFieldView renders a field of the selected auth type, secrets are
+// masked unless revealed.
+func authFieldView(m model, i int) string {
+	field := m.auth.options[m.auth.cursor].fields[i]
+	if !m.revealSecrets && sensitive(m.auth.cursor, i) {
+		field.EchoMode = textinput.EchoPassword
+	}
+	return field.View()
+}
+
 func getAuthView(m model) string {
 	style := primary.Border(lg.NormalBorder(), false, false, true, false).Width(20)
 	var prefix0, prefix1 string
@@ -238,11 +335,13 @@ func getAuthView(m model) string {
 		width := requestPaginatorStyle.GetWidth()
 		height := requestPaginatorStyle.GetHeight()
 		return lg.NewStyle().Width(width).Height(height).Align(lg.Center, lg.Center).Render("󰌿\n\nSelect an auth type from above")
+	case oauth2Auth, awsAuth, apiKeyAuth, hmacAuth, jwtAuth:
+		return renderLabeledAuth(m, auth)
 	case customAuth:
 		if view == authContentView && auth.cursor == 0 {
 			style = style.Inherit(focused)
 		}
-		return lg.JoinHorizontal(lg.Left, "Custom ", style.Width(50).Render(auth.fields[0].View()))
+		return lg.JoinHorizontal(lg.Left, "Custom ", style.Width(50).Render(authFieldView(m, 0)))
 	case basicAuth, digestAuth:
 		prefix0, prefix1 = "Username ", "Password "
 	case tokenAuth:
@@ -258,7 +357,80 @@ func getAuthView(m model) string {
 			s1 = s1.BorderBottomForeground(white)//Inherit(focused)
 		}
 	}
-	return lg.JoinHorizontal(lg.Left, prefix0, s0.Render(auth.fields[0].View())) + "\n" + lg.JoinHorizontal(lg.Left, prefix1, s1.Render(auth.fields[1].View()))
+	return lg.JoinHorizontal(lg.Left, prefix0, s0.Render(authFieldView(m, 0))) + "\n" + lg.JoinHorizontal(lg.Left, prefix1, s1.Render(authFieldView(m, 1)))
+}
+
+// authLabels are the labels of the auth types listing their fields one per
+// line, they scroll with the cursor when they don't fit the pane.
+var authLabels = map[int][]string{
+	oauth2Auth: oauthLabels, awsAuth: awsLabels, apiKeyAuth: apiKeyLabels, hmacAuth: hmacLabels, jwtAuth: jwtLabels,
+}
+
+// renderAuthHeader returns the lines shown above the fields of the auth type
+func renderAuthHeader(m model) string {
+	switch m.auth.cursor {
+	case oauth2Auth:
+		return primary.Width(15).Render("Token") + renderTokenDetails(m, m.auth.options[oauth2Auth])
+	}
+	return ""
+}
+
+// authFieldsShown returns the range of the fields that fit below the
+// header, scrolled so the selected one is visible.
+func authFieldsShown(m model) (offset, count int) {
+	auth := m.auth.options[m.auth.cursor]
+	// the pane content is surrounded by 4 lines, every field takes 2
+	height := requestPaginatorStyle.GetHeight() - 4
+	if header := renderAuthHeader(m); header != "" {
+		height -= lg.Height(header)
+	}
+	count = min(max(height/2, 1), len(auth.fields))
+	offset = max(0, auth.cursor-count+1)
+	return offset, count
+}
+
+func renderLabeledAuth(m model, auth *authType) string {
+	var lines []string
+	if header := renderAuthHeader(m); header != "" {
+		lines = append(lines, header)
+	}
+	label := primary.Width(15)
+	offset, count := authFieldsShown(m)
+	for i := offset; i < offset+count; i++ {
+		style := primary.Border(lg.NormalBorder(), false, false, true, false).Width(labeledFieldWidth + 1).BorderBottomForeground(placeHolderColor)
+		if view == authContentView && auth.cursor == i {
+			style = style.BorderBottomForeground(white)
+		}
+		lines = append(lines, lg.JoinHorizontal(lg.Left, label.Render(authLabels[m.auth.cursor][i]), style.Render(authFieldView(m, i))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderTokenDetails(m model, auth *authType) string {
+	token, ok := m.tokens[oauthConfigFrom(auth.fields).key()]
+	if !ok {
+		return primary.Foreground(placeHolderColor).Render("none yet, acquired on send")
+	}
+	access := token.AccessToken
+	if len(access) > 12 {
+		access = access[:4] + "…" + access[len(access)-4:]
+	}
+	details := []string{cmp.Or(token.TokenType, "Bearer"), access}
+	switch {
+	case token.ExpiresAt.IsZero():
+		details = append(details, "no expiry")
+	case token.valid():
+		details = append(details, "expires "+humanize.Time(token.ExpiresAt))
+	default:
+		details = append(details, primary.Foreground(orange).Render("expired "+humanize.Time(token.ExpiresAt)))
+	}
+	if token.RefreshToken != "" {
+		details = append(details, "refreshable")
+	}
+	if token.Scope != "" {
+		details = append(details, "scope "+token.Scope)
+	}
+	return primary.MaxWidth(rightPanelWidth - 15).Render(strings.Join(details, "  "))
 }
 
 func renderNameValueFields(f inputFields, focusedView int) string {
@@ -313,6 +485,10 @@ func renderLeftStatusLine(m model) (string, string, string) {
 }
 
 func renderStatusLine(m model) string {
+	if m.err != nil {
+		message := strings.ReplaceAll("✗ "+m.err.Error(), "\n", " ")
+		return secondary.PaddingLeft(1).Foreground(red).MaxWidth(rightPanelWidth).Render(message)
+	}
 	status, duration, size := renderLeftStatusLine(m)
 	relativeTime := renderRelativeTime(m)
 	combined := status + secondary.Render(" ") + duration + secondary.Render(" ") + size
@@ -328,19 +504,43 @@ func renderHistory(m model) string {
 	}
 	space := primary.Render(" ")
 	var b strings.Builder
-	b.WriteString("\n")
+	var hints []string
+	for _, hint := range []struct {
+		desc    string
+		binding key.Binding
+	}{
+		{"restore", keys.Restore},
+		{"copy as new request", keys.Copy},
+		{"show the request sent", keys.Inspect},
+		{"pin", keys.Pin},
+		{"delete", keys.Delete},
+	} {
+		hints = append(hints, hint.desc+" ("+hint.binding.Help().Key+")")
+	}
+	b.WriteString("\n" + secondary.Foreground(placeHolderColor).Render(strings.Join(hints, " ")))
 	for i, response := range m.requests.items[m.requests.cursor].Responses {
 		cursor := "  "
 		if m.history.cursor == i {
 			cursor = "> "
 		}
+		if response.Pinned {
+			cursor = cursor[:1] + lg.NewStyle().Foreground(orange).Render("⚑")
+		}
 		status := getStatusStyle(response.Status).Render(response.Status)
 		method_with_url := focused.Render(response.RequestMethod + " " + response.RequestUrl)
 		duration := focused.Render(response.Duration)
 		size := focused.Render(response.Size)
 		line := cursor + strings.Join([]string{status, method_with_url, duration, size}, space)
+		if bar := timingBar(response.Timing, historyBarWidth, -1); bar != "" {
+			line += space + bar
+		}
 		b.WriteString("\n" + line)
 	}
+	if m.showSent {
+		res := m.requests.items[m.requests.cursor].Responses[m.history.cursor]
+		sent := lg.NewStyle().Width(rightPanelWidth-2).Border(lg.NormalBorder(), true, false, false).Render(renderSnapshot(res.Request))
+		b.WriteString("\n" + sent)
+	}
 	return b.String()
 }
 
@@ -353,6 +553,15 @@ func renderResponseBody(m model) string {
 	}
 }
 
+func renderResponseRaw(m model) string {
+	switch view {
+	case responseRawView:
+		return responseFocusedStyle.Render(m.resRaw.View())
+	default:
+		return responseUnFocusedStyle.Render(m.resRaw.View())
+	}
+}
+
 func renderResponseHeaders(m model) string {
 	switch view {
 	case responseHeadersView:
@@ -363,19 +572,49 @@ func renderResponseHeaders(m model) string {
 }
 
 func renderResponseCookies(m model) string {
+	// warnings of the selected cookie, or how many would be rejected
+	warning := ""
+	rejected := 0
+	for _, row := range m.resCookies.Rows() {
+		if row[5] != "" {
+			rejected++
+		}
+	}
+	if row := m.resCookies.SelectedRow(); view == responseCookiesView && row != nil && row[5] != "" {
+		warning = "⚠ " + row[5]
+	} else if rejected > 0 {
+		warning = fmt.Sprintf("⚠ a browser would reject %d of the cookies", rejected)
+	}
+	warning = lg.NewStyle().Foreground(orange).MaxWidth(rightPanelWidth - 2).Render(warning)
 	switch view {
 	case responseCookiesView:
-		return tableFocusedStyle.Render(m.resCookies.View())
+		return tableFocusedStyle.Render(m.resCookies.View() + "\n" + warning)
 	default:
-		return tableUnFocusedStyle.Render(m.resCookies.View())
+		return tableUnFocusedStyle.Render(m.resCookies.View() + "\n" + warning)
 	}
 }
 
 func (m model) View() string {
-	helpKeys := " (n) 󰆴 (d)\n"
-	requestNames := renderRequestNames(m)
+	leftSide := helpKeys + renderSortMode(m) + "\n" + renderRequestNames(m)
+	if view == paletteView {
+		return lg.JoinHorizontal(lg.Top, leftSide, renderPalette(m))
+	}
+	if m.showHelp {
+		return lg.JoinHorizontal(lg.Top, leftSide, renderHelp(m))
+	}
+	if view == cookiesView {
+		return lg.JoinHorizontal(lg.Top, leftSide, renderCookies(m))
+	}
+	if view == historyBrowserView {
+		return lg.JoinHorizontal(lg.Top, leftSide, renderHistoryBrowser(m))
+	}
+	if view == searchView {
+		return lg.JoinHorizontal(lg.Top, leftSide, renderSearch(m))
+	}
+	if m.confirmQuit || m.confirmClose {
+		return lg.JoinHorizontal(lg.Top, leftSide, renderUnsavedPrompt(m))
+	}
 	if len(m.requests.items) == 0 {
-		leftSide := helpKeys + "\n" + requestNames
 		rightTop := secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render("")
 		requestSettings := primary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render("")
 		rightSide := rightTop + "\n" + requestSettings
@@ -398,7 +637,6 @@ func (m model) View() string {
 
 	status_line := secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(renderStatusLine(m) + renderHistory(m))
 
-	leftSide := helpKeys + "\n" + requestNames
 	rightTop := secondary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(lg.JoinHorizontal(lg.Top, method, url, button))
 	requestSettings := primary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(body_setting + auth_setting + query_setting + header_setting)
 
@@ -413,6 +651,7 @@ func (m model) View() string {
 	case 3:
 		content = renderNameValueFields(m.headers, headersContentView)
 	}
+	requestContent := content
 	request_paginator := requestPaginatorStyle.Inherit(primary).Render("\n" + content + "\n\n" + m.requestPaginator.View())
 
 	switch m.responsePaginator.Page {
@@ -423,21 +662,57 @@ func (m model) View() string {
 	case 2:
 		content = renderResponseCookies(m)
 	case 3:
-		content = lg.NewStyle().Border(lg.NormalBorder()).Render(m.resLogs)
+		logs := m.resLogs
+		if waterfall := renderWaterfall(m.resTiming, rightPanelWidth); waterfall != "" {
+			logs = waterfall + "\n\n" + logs
+		}
+		content = lg.NewStyle().Border(lg.NormalBorder()).Width(rightPanelWidth).MaxHeight(m.resBody.Height + 2).Render(logs)
+	case 4:
+		content = renderResponseRaw(m)
 	}
 	response_paginator := content + "\n\n" + m.responsePaginator.View()
 
-	rightSide := rightTop
+	// register the clickable zones while stacking the right side
+	resetZones(m)
+	x := lg.Width(leftSide)
+	tabs := renderTabs(m)
+	tabX := x
+	for i, tab := range tabs {
+		addZone(tabsZone, i, tabX, 0, lg.Width(tab), 1)
+		tabX += lg.Width(tab) + 1
+	}
+	y := 1 + lg.Height(rightTop)
+	addZones(x+1, 2, 1, []int{httpMethodView, urlView, sendView}, method, url, button)
+	rightSide := renderTabBar(tabs) + "\n" + rightTop
 	if len(methodOptions) > 0 {
+		addListZones(methodOptionsView, x+1, y+1, rightPanelWidth, len(m.method.options))
 		rightSide += "\n" + primary.Render(methodOptions)
+		y += lg.Height(methodOptions)
 	}
+	addZones(x+1, y+1, 1, []int{bodyTypeView, authTypeView, queryView, headersView}, body_setting, auth_setting, query_setting, header_setting)
 	rightSide += "\n" + requestSettings
+	y += lg.Height(requestSettings)
 
 	if len(body_options) > 0 {
+		addListZones(bodyOptionsView, x+1, y+1, rightPanelWidth, len(m.body.options))
 		rightSide += "\n" + primary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(body_options)
+		y += lg.Height(body_options) + 2
 	} else if len(auth_options) > 0 {
+		addListZones(authOptionsView, x+1, y+1, rightPanelWidth, len(m.auth.options))
 		rightSide += "\n" + primary.Width(rightPanelWidth).Border(lg.NormalBorder()).Render(auth_options)
-	}
+		y += lg.Height(auth_options) + 2
+	}
+	addRequestContentZones(m, x+1, y+2, lg.Height(requestContent))
+	addDotZones(x+1, y+lg.Height("\n"+requestContent+"\n\n"), len(m.requestContents), requestPagesZone)
+	y += lg.Height(request_paginator)
+	addZone(historyView, 0, x, y, rightPanelWidth+2, 2)
+	if view == historyOptionsView {
+		// status line and hint come before the entries
+		addListZones(historyOptionsView, x+1, y+3, rightPanelWidth, len(m.requests.items[m.requests.cursor].Responses))
+	}
+	y += lg.Height(status_line)
+	addZone(responseView+m.responsePaginator.Page, 0, x, y, rightPanelWidth+2, lg.Height(content))
+	addDotZones(x, y+lg.Height(content)+1, m.responsePaginator.TotalPages, responsePagesZone)
 	rightSide += "\n" + request_paginator + "\n" + status_line + "\n" + response_paginator
 
 	return lg.NewStyle().Render(lg.JoinHorizontal(lg.Top, leftSide, rightSide))
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+const (
+	// rawBodyLimit is how much of each body the raw view keeps
+	rawBodyLimit = 64 << 10
+	// rawCaptureLimit is how much of each message is captured, the head
+	// and the body kept included
+	rawCaptureLimit = rawBodyLimit + 16<<10
+)
+
+// wireTransport is the transport of every request, it keeps the connections
+// it dials so the bytes of HTTP/1.x can be captured as they go through.
+// HTTP/2 is binary, those connections are left alone.
+var wireTransport = tlsStateTransport{newWireTransport()}
+
+// tlsStateTransport sets the TLS state of the responses received over a
+// captureConn, the transport only sets it for a *tls.Conn.
+type tlsStateTransport struct {
+	*http.Transport
+}
+
+func (t tlsStateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var state *tls.ConnectionState
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if c, ok := info.Conn.(*captureConn); ok {
+				state = c.tlsState
+			}
+		},
+	}
+	res, err := t.Transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err == nil && res.TLS == nil {
+		res.TLS = state
+	}
+	return res, err
+}
+
+func newWireTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &captureConn{Conn: conn}, nil
+	}
+	// the TLS connections are dialed here to capture the decrypted bytes,
+	// the transport would only give the encrypted ones
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		host, _, _ := net.SplitHostPort(addr)
+		config := &tls.Config{}
+		if t.TLSClientConfig != nil {
+			config = t.TLSClientConfig.Clone()
+		}
+		config.ServerName, config.NextProtos = host, []string{"h2", "http/1.1"}
+		trace := httptrace.ContextClientTrace(ctx)
+		if trace != nil && trace.TLSHandshakeStart != nil {
+			trace.TLSHandshakeStart()
+		}
+		tlsConn := tls.Client(conn, config)
+		err = tlsConn.HandshakeContext(ctx)
+		if trace != nil && trace.TLSHandshakeDone != nil {
+			trace.TLSHandshakeDone(tlsConn.ConnectionState(), err)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		// the transport only speaks HTTP/2 over a *tls.Conn
+		if tlsConn.ConnectionState().NegotiatedProtocol == "h2" {
+			return tlsConn, nil
+		}
+		state := tlsConn.ConnectionState()
+		return &captureConn{Conn: tlsConn, tlsState: &state}, nil
+	}
+	return t
+}
+
+// captureConn copies what goes through the connection to the capture of
+// the request using it, connections are reused so it is set on GotConn.
+type captureConn struct {
+	net.Conn
+	mu      sync.Mutex
+	capture *wireCapture
+	// the handshake of the *tls.Conn wrapped, if it is one
+	tlsState *tls.ConnectionState
+}
+
+func (c *captureConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.mu.Lock()
+	c.capture.add(false, p[:n])
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *captureConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.mu.Lock()
+	c.capture.add(true, p[:n])
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *captureConn) use(capture *wireCapture) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capture = capture
+}
+
+type wireChunk struct {
+	sent bool
+	data []byte
+	// the bytes past rawCaptureLimit, counted but not kept
+	dropped int
+}
+
+// wireCapture is what a request sent and received, redirects and auth
+// retries included, in order.
+type wireCapture struct {
+	mu     sync.Mutex
+	chunks []wireChunk
+}
+
+func (w *wireCapture) add(sent bool, data []byte) {
+	if w == nil || len(data) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.append(sent, data)
+}
+
+// append adds the data to the last chunk if it goes the same way, w.mu is
+// held by the caller
+func (w *wireCapture) append(sent bool, data []byte) {
+	last := len(w.chunks) - 1
+	if last < 0 || w.chunks[last].sent != sent {
+		w.chunks = append(w.chunks, wireChunk{sent: sent})
+		last++
+	}
+	c := &w.chunks[last]
+	keep := min(len(data), max(rawCaptureLimit-len(c.data), 0))
+	c.data = append(c.data, data[:keep]...)
+	c.dropped += len(data) - keep
+}
+
+func (w *wireCapture) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if c, ok := info.Conn.(*captureConn); ok {
+				c.use(w)
+			}
+		},
+	}
+}
+
+// raw renders the capture, or dumps the request and the response when the
+// connection couldn't be captured (HTTP/2 or HTTPS through a proxy).
+func (w *wireCapture) raw(req *http.Request, res *http.Response, body []byte) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	protocol := res.Proto
+	if res.TLS != nil {
+		protocol += ", " + tls.VersionName(res.TLS.Version)
+		if res.TLS.NegotiatedProtocol != "" {
+			protocol += fmt.Sprintf(" (ALPN %s)", res.TLS.NegotiatedProtocol)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Protocol: %s\n", protocol)
+	if len(w.chunks) == 0 {
+		b.WriteString("Not captured from the connection, shown as HTTP/1.1\n")
+		if req.GetBody != nil {
+			req.Body, _ = req.GetBody()
+		}
+		sent, err := httputil.DumpRequestOut(req, true)
+		if err != nil {
+			sent = []byte(err.Error())
+		}
+		received, err := httputil.DumpResponse(res, false)
+		if err != nil {
+			received = []byte(err.Error())
+		}
+		w.append(true, sent)
+		w.append(false, received)
+		w.append(false, body)
+	}
+	for _, chunk := range w.chunks {
+		direction := "Received"
+		if chunk.sent {
+			direction = "Sent"
+		}
+		fmt.Fprintf(&b, "\n── %s (%d bytes) ──\n%s\n", direction, len(chunk.data)+chunk.dropped, rawMessage(chunk.data, chunk.dropped))
+	}
+	return b.String()
+}
+
+// rawMessage keeps the head as is, a body that isn't text (compressed,
+// binary) or is too big is summed up. The dropped bytes are the end of the
+// message that wasn't captured.
+func rawMessage(data []byte, dropped int) string {
+	head, body, found := bytes.Cut(data, []byte("\r\n\r\n"))
+	if !found {
+		head, body = nil, data
+	}
+	var b strings.Builder
+	if head != nil {
+		b.Write(head)
+		b.WriteString("\r\n\r\n")
+	}
+	size := len(body) + dropped
+	switch {
+	case size == 0:
+	case !textual(body):
+		fmt.Fprintf(&b, "[%d bytes of binary data]", size)
+	case size > rawBodyLimit:
+		kept := wholeRunes(body[:min(rawBodyLimit, len(body))])
+		fmt.Fprintf(&b, "%s\n[%d more bytes]", kept, size-len(kept))
+	default:
+		b.Write(body)
+	}
+	return b.String()
+}
+
+// textual reports if the body is text, a rune cut at the end by the
+// capture limit doesn't count.
+func textual(body []byte) bool {
+	body = wholeRunes(body)
+	return utf8.Valid(body) && bytes.IndexByte(body, 0) < 0
+}
+
+// wholeRunes drops the start of a rune at the end of b
+func wholeRunes(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// setRawContent shows the raw messages, a carriage return would move the
+// cursor of the terminal so the line endings are shown as plain newlines.
+func setRawContent(v *viewport.Model, raw string) {
+	v.SetContent(strings.ReplaceAll(raw, "\r\n", "\n"))
+	v.GotoTop()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+)
+
+// captured sends a request through a wire transport trusting the server
+func captured(t *testing.T, server *httptest.Server, req *http.Request) (*http.Response, string) {
+	t.Helper()
+	transport := tlsStateTransport{newWireTransport()}
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+	defer transport.CloseIdleConnections()
+	wire := &wireCapture{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), wire.clientTrace()))
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body := make([]byte, 0, 1<<20)
+	for buf := make([]byte, 32<<10); ; {
+		n, err := res.Body.Read(buf)
+		body = append(body, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	return res, newRedactor(nil, nil).lines(wire.raw(req, res, body))
+}
+
+// HTTPS/1.1 is captured in clear and keeps its TLS state
+func TestWireTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "server-secret"})
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	req, _ := http.NewRequest("GET", server.URL+"/path", nil)
+	req.Header.Set("Cookie", "session=client-secret")
+	req.Header.Set("Authorization", "Bearer token-secret")
+	res, raw := captured(t, server, req)
+
+	if res.TLS == nil || res.TLS.Version != tls.VersionTLS13 {
+		t.Fatalf("res.TLS = %+v, want the TLS 1.3 state", res.TLS)
+	}
+	for _, want := range []string{"Protocol: HTTP/1.1, TLS 1.3 (ALPN http/1.1)", "GET /path HTTP/1.1", "hello",
+		"Cookie: " + redacted, "Set-Cookie: " + redacted, "Authorization: " + redacted} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("the capture lacks %q:\n%s", want, raw)
+		}
+	}
+	for _, secret := range []string{"server-secret", "client-secret", "token-secret"} {
+		if strings.Contains(raw, secret) {
+			t.Errorf("the capture has %q", secret)
+		}
+	}
+}
+
+// a big body is counted, not kept
+func TestWireCaptureLimit(t *testing.T) {
+	const size = 1 << 20
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("é", size/2)))
+	}))
+	defer server.Close()
+	req, _ := http.NewRequest("GET", server.URL, nil)
+
+	w := &wireCapture{}
+	w.add(false, []byte("HTTP/1.1 200 OK\r\n\r\n"))
+	w.add(false, []byte(strings.Repeat("é", size/2)))
+	if c := w.chunks[0]; len(c.data) != rawCaptureLimit || len(c.data)+c.dropped != size+19 {
+		t.Errorf("chunk of %d bytes and %d dropped", len(c.data), c.dropped)
+	}
+
+	_, raw := captured(t, server, req)
+	if !strings.HasSuffix(raw, " more bytes]\n") || strings.Contains(raw, "binary") || len(raw) > rawCaptureLimit {
+		t.Errorf("the big body isn't summed up as text:\n%s", raw[len(raw)-100:])
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	head := "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\n"
+	tests := []struct {
+		name, body string
+		dropped    int
+		want       string
+	}{
+		{"text", "hello", 0, head + "hello"},
+		{"empty", "", 0, head},
+		{"binary", "\x00\x01\x02", 0, head + "[3 bytes of binary data]"},
+		{"binary cut", "\xff\xfe", 10, head + "[12 bytes of binary data]"},
+		// the capture stopped in the middle of a rune
+		{"rune cut", strings.Repeat("a", rawBodyLimit-1) + "\xc3", 1, head + strings.Repeat("a", rawBodyLimit-1) + "\n[2 more bytes]"},
+	}
+	for _, tt := range tests {
+		if got := rawMessage([]byte(head+tt.body), tt.dropped); got != tt.want {
+			t.Errorf("%s: %.80q, want %.80q", tt.name, got, tt.want)
+		}
+	}
+}